@@ -0,0 +1,200 @@
+package core
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrAdmissionTimeout is returned when a transaction waited longer than [AdmissionControl.MaxWait] to be admitted.
+var ErrAdmissionTimeout = errors.New("timed out waiting for transaction admission")
+
+// Priority is the priority class of an outermost transaction.
+// When the admission control is enabled, waiting transactions are admitted by priority, then in order of arrival.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+
+	priorityCount = int(PriorityHigh) + 1
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context in which the outermost transactions are admitted with the given priority.
+// Transactions are admitted with [PriorityNormal] by default.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func priorityFromContext(ctx context.Context) Priority {
+	priority, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok {
+		return PriorityNormal
+	}
+
+	return min(max(priority, PriorityLow), PriorityHigh)
+}
+
+// AdmissionControl configures the admission control of the outermost transactions.
+type AdmissionControl struct {
+	// MaxConcurrentTransactions is the maximum number of outermost transactions running concurrently, it must be positive.
+	// It should be set lower than the maximum number of open connections of the pool,
+	// so that some connections are always available to the queries made outside of transactions.
+	MaxConcurrentTransactions int
+
+	// MaxWait is the maximum duration a transaction can wait to be admitted before failing with [ErrAdmissionTimeout].
+	// Zero means waiting until the context is done.
+	MaxWait time.Duration
+
+	// OnWait is called with the time every outermost transaction waited before being admitted or rejected.
+	// It can be used to feed a histogram metric.
+	OnWait func(priority Priority, wait time.Duration, err error)
+}
+
+// AdmissionStats contains the admission control statistics.
+type AdmissionStats struct {
+	InFlight int // The number of outermost transactions currently admitted.
+	Waiting  int // The number of outermost transactions currently waiting to be admitted.
+
+	WaitCount    int64         // The total number of transactions that had to wait to be admitted.
+	WaitDuration time.Duration // The total time waited to be admitted.
+	Rejected     int64         // The total number of transactions rejected because of a timeout or a canceled context.
+}
+
+// SetAdmissionControl limits the number of outermost transactions running concurrently.
+// It panics if [AdmissionControl.MaxConcurrentTransactions] is not positive, as no transaction could be admitted.
+func (t *Transactor[DB, Tx]) SetAdmissionControl(config AdmissionControl) {
+	if config.MaxConcurrentTransactions <= 0 {
+		panic(fmt.Errorf("invalid admission control: MaxConcurrentTransactions must be positive, got %d", config.MaxConcurrentTransactions))
+	}
+
+	t.admission = &admissionLimiter{AdmissionControl: config}
+}
+
+// AdmissionStats returns the admission control statistics.
+// It returns zero stats if the admission control is disabled.
+func (t *Transactor[DB, Tx]) AdmissionStats() AdmissionStats {
+	return t.admission.stats()
+}
+
+type admissionLimiter struct {
+	AdmissionControl
+
+	mu       sync.Mutex
+	inFlight int
+	waiters  [priorityCount]list.List // Lists of channels closed on admission
+	counters AdmissionStats
+}
+
+// acquire waits for the transaction to be admitted.
+// It returns a function to call once the transaction is done.
+// A nil limiter admits every transaction.
+func (l *admissionLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	priority := priorityFromContext(ctx)
+
+	l.mu.Lock()
+	if l.inFlight < l.MaxConcurrentTransactions {
+		l.inFlight++
+		l.mu.Unlock()
+		l.observe(priority, 0, nil)
+
+		return l.release, nil
+	}
+
+	admitted := make(chan struct{})
+	waiter := l.waiters[priority].PushBack(admitted)
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if l.MaxWait > 0 {
+		timer := time.NewTimer(l.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	start := time.Now()
+	var err error
+	select {
+	case <-admitted:
+	case <-ctx.Done():
+		err = fmt.Errorf("failed to wait for transaction admission: %w", context.Cause(ctx))
+	case <-timeout:
+		err = ErrAdmissionTimeout
+	}
+	wait := time.Since(start)
+
+	l.mu.Lock()
+	if err != nil {
+		select {
+		case <-admitted:
+			// The transaction was admitted concurrently, so it can run anyway
+			err = nil
+
+		default:
+			l.waiters[priority].Remove(waiter)
+			l.counters.Rejected++
+		}
+	}
+	l.counters.WaitCount++
+	l.counters.WaitDuration += wait
+	l.mu.Unlock()
+
+	l.observe(priority, wait, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.release, nil
+}
+
+// release hands the slot of a finished transaction over to the next waiting transaction, if any.
+func (l *admissionLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for priority := PriorityHigh; priority >= PriorityLow; priority-- {
+		if waiter := l.waiters[priority].Front(); waiter != nil {
+			l.waiters[priority].Remove(waiter)
+			if admitted, ok := waiter.Value.(chan struct{}); ok {
+				close(admitted)
+			}
+
+			return
+		}
+	}
+
+	l.inFlight--
+}
+
+func (l *admissionLimiter) observe(priority Priority, wait time.Duration, err error) {
+	if l.OnWait != nil {
+		l.OnWait(priority, wait, err)
+	}
+}
+
+func (l *admissionLimiter) stats() AdmissionStats {
+	if l == nil {
+		return AdmissionStats{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.counters
+	stats.InFlight = l.inFlight
+	for priority := range l.waiters {
+		stats.Waiting += l.waiters[priority].Len()
+	}
+
+	return stats
+}
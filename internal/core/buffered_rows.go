@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+)
+
+// BufferedRows are the results of a query read entirely, so that its connection is free for the next statement.
+// They're replayed by the DB handler returned by [NewBufferDB], as the [sql.Rows] and [sql.Row] types can't be created otherwise.
+// All the rows are held in memory, so the memory used grows with the size of the results.
+type BufferedRows struct {
+	columns     []string
+	columnTypes []*sql.ColumnType
+	values      [][]any
+	err         error
+}
+
+// ReadRows reads the rows entirely and closes them.
+func ReadRows(rows *sql.Rows) (*BufferedRows, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	buffered := &BufferedRows{columns: columns, columnTypes: columnTypes}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		buffered.values = append(buffered.values, values)
+	}
+
+	return buffered, rows.Err() //nolint:wrapcheck
+}
+
+// FailedRows returns buffered rows replayed as the given error.
+func FailedRows(err error) *BufferedRows {
+	return &BufferedRows{err: err}
+}
+
+type bufferedRowsKey struct{}
+
+// BufferedRowsToContext returns a copy of the context with the rows to replay by the next query of the buffer DB handler.
+func BufferedRowsToContext(ctx context.Context, rows *BufferedRows) context.Context {
+	return context.WithValue(ctx, bufferedRowsKey{}, rows)
+}
+
+// NewBufferDB returns a DB handler replaying the buffered rows found in the context of its queries, whatever the query.
+func NewBufferDB() *sql.DB {
+	return sql.OpenDB(bufferConnector{})
+}
+
+var errBufferNotSupported = errors.New("operation not supported on buffered rows")
+
+type (
+	bufferConnector struct{}
+	bufferDriver    struct{}
+	bufferConn      struct{}
+)
+
+func (bufferConnector) Connect(context.Context) (driver.Conn, error) { return bufferConn{}, nil }
+func (bufferConnector) Driver() driver.Driver                        { return bufferDriver{} }
+
+func (bufferDriver) Open(string) (driver.Conn, error) { return bufferConn{}, nil }
+
+func (bufferConn) Prepare(string) (driver.Stmt, error) { return nil, errBufferNotSupported }
+func (bufferConn) Close() error                        { return nil }
+func (bufferConn) Begin() (driver.Tx, error)           { return nil, errBufferNotSupported }
+
+func (bufferConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	rows, ok := ctx.Value(bufferedRowsKey{}).(*BufferedRows)
+	if !ok {
+		return nil, errBufferNotSupported
+	}
+	if rows.err != nil {
+		return nil, rows.err
+	}
+
+	return &bufferDriverRows{BufferedRows: rows}, nil
+}
+
+// bufferDriverRows replays the buffered rows, along with the column types of the original rows.
+type bufferDriverRows struct {
+	*BufferedRows
+	next int
+}
+
+func (r *bufferDriverRows) Columns() []string {
+	return r.columns
+}
+
+func (r *bufferDriverRows) Close() error {
+	return nil
+}
+
+func (r *bufferDriverRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+
+	for i, value := range r.values[r.next] {
+		dest[i] = value
+	}
+	r.next++
+
+	return nil
+}
+
+func (r *bufferDriverRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.columnTypes[index].DatabaseTypeName()
+}
+
+func (r *bufferDriverRows) ColumnTypeLength(index int) (int64, bool) {
+	return r.columnTypes[index].Length()
+}
+
+func (r *bufferDriverRows) ColumnTypeNullable(index int) (bool, bool) {
+	return r.columnTypes[index].Nullable()
+}
+
+func (r *bufferDriverRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	return r.columnTypes[index].DecimalSize()
+}
+
+func (r *bufferDriverRows) ColumnTypeScanType(index int) reflect.Type {
+	return r.columnTypes[index].ScanType()
+}
@@ -0,0 +1,244 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all the transactions through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen makes the transactions fail fast with a [*CircuitOpenError].
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe transactions through to check whether the database recovered.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitOpenError is returned when starting a transaction while the circuit breaker is open.
+type CircuitOpenError struct {
+	// RetryAt is the time at which the circuit breaker will let probe transactions through.
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open until %s", e.RetryAt.Format(time.RFC3339))
+}
+
+// CircuitBreaker configures the circuit breaker around the outermost transactions.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failed transactions tripping the circuit breaker, it must be positive.
+	FailureThreshold int
+
+	// OpenDuration is the duration the circuit breaker stays open before letting probe transactions through, it must be positive.
+	OpenDuration time.Duration
+
+	// HalfOpenProbes is the maximum number of probe transactions running concurrently while the circuit breaker is half-open.
+	// Defaults to 1.
+	HalfOpenProbes int
+
+	// IsFailure reports whether an error returned when beginning or committing a transaction counts as a failure.
+	// The errors returned by the callback of the transactions aren't reported. Defaults to detecting connection errors.
+	IsFailure func(error) bool
+
+	// OnStateChange is called when the state of the circuit breaker changes.
+	// It must not block nor call the transactor.
+	OnStateChange func(from, to CircuitState)
+}
+
+// SetCircuitBreaker makes the outermost transactions fail fast after consecutive failures.
+// The connection errors of the driver count as failures unless [CircuitBreaker.IsFailure] is set.
+// It panics if [CircuitBreaker.FailureThreshold] or [CircuitBreaker.OpenDuration] is not positive.
+func (t *Transactor[DB, Tx]) SetCircuitBreaker(config CircuitBreaker, isConnectionError func(error) bool) {
+	if config.FailureThreshold <= 0 {
+		panic(fmt.Errorf("invalid circuit breaker: FailureThreshold must be positive, got %d", config.FailureThreshold))
+	}
+	if config.OpenDuration <= 0 {
+		panic(fmt.Errorf("invalid circuit breaker: OpenDuration must be positive, got %s", config.OpenDuration))
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = func(err error) bool {
+			return isNetworkError(err) || isConnectionError(err)
+		}
+	}
+
+	t.breaker = &circuitBreaker{CircuitBreaker: config}
+}
+
+// CircuitState returns the current state of the circuit breaker.
+// It returns [CircuitClosed] if the circuit breaker is disabled.
+func (t *Transactor[DB, Tx]) CircuitState() CircuitState {
+	return t.breaker.currentState()
+}
+
+type circuitBreaker struct {
+	CircuitBreaker
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	retryAt  time.Time
+	probes   int
+}
+
+// circuitBreakerCall is a transaction let through by the circuit breaker.
+type circuitBreakerCall struct {
+	breaker *circuitBreaker
+	probe   bool
+}
+
+// allow checks whether a transaction can start.
+// A nil circuit breaker lets every transaction through.
+func (b *circuitBreaker) allow() (*circuitBreakerCall, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Now().Before(b.retryAt) {
+			return nil, &CircuitOpenError{RetryAt: b.retryAt}
+		}
+
+		b.setState(CircuitHalfOpen)
+		b.probes = 0
+		fallthrough
+
+	case CircuitHalfOpen:
+		if b.probes >= b.HalfOpenProbes {
+			return nil, &CircuitOpenError{RetryAt: b.retryAt}
+		}
+
+		b.probes++
+		return &circuitBreakerCall{breaker: b, probe: true}, nil
+
+	default:
+		return &circuitBreakerCall{breaker: b}, nil
+	}
+}
+
+// done records the outcome of the transaction.
+func (c *circuitBreakerCall) done(err error) {
+	if c == nil {
+		return
+	}
+
+	b := c.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := err != nil && b.IsFailure(err)
+
+	if c.probe {
+		b.probes--
+		if b.state != CircuitHalfOpen {
+			return
+		}
+
+		if failed {
+			b.trip()
+		} else {
+			b.failures = 0
+			b.setState(CircuitClosed)
+		}
+
+		return
+	}
+
+	if b.state != CircuitClosed {
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.FailureThreshold {
+		b.trip()
+	}
+}
+
+// abandon releases a transaction that didn't start, without recording any outcome.
+func (c *circuitBreakerCall) abandon() {
+	if c == nil || !c.probe {
+		return
+	}
+
+	c.breaker.mu.Lock()
+	defer c.breaker.mu.Unlock()
+
+	c.breaker.probes--
+}
+
+func (b *circuitBreaker) trip() {
+	b.retryAt = time.Now().Add(b.OpenDuration)
+	b.setState(CircuitOpen)
+}
+
+func (b *circuitBreaker) setState(state CircuitState) {
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+	b.state = state
+	if b.OnStateChange != nil {
+		b.OnStateChange(from, state)
+	}
+}
+
+func (b *circuitBreaker) currentState() CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// isNetworkError reports whether the error is a failure of the connection to the database, whatever the driver.
+// The expired and canceled contexts aren't, even though their errors can be a [net.Error].
+func isNetworkError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var (
+		opErr  *net.OpError
+		dnsErr *net.DNSError
+	)
+
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &opErr) ||
+		errors.As(err, &dnsErr)
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// txKey is the context key of the transactions, distinct for each type of DB handler
+// so that the transactions of different drivers don't mix.
+type txKey[DB any] struct{}
+
+// TxToContext returns a copy of the context holding the DB handler of a transaction.
+func TxToContext[DB any](ctx context.Context, tx DB) context.Context {
+	return context.WithValue(ctx, txKey[DB]{}, tx)
+}
+
+// TxFromContext returns the DB handler of the transaction of the context, if any.
+func TxFromContext[DB any](ctx context.Context) (DB, bool) {
+	tx, ok := ctx.Value(txKey[DB]{}).(DB)
+	return tx, ok
+}
+
+// IsWithinTransaction reports whether the context holds a transaction.
+func IsWithinTransaction[DB any](ctx context.Context) bool {
+	return ctx.Value(txKey[DB]{}) != nil
+}
+
+// WithoutTransaction returns a copy of the context without the current transaction, if any, nor its state:
+// its lock, its strict mode checks, its subtransaction budget, and its read-only flag.
+func WithoutTransaction[DB any](ctx context.Context) context.Context {
+	for _, key := range []any{txKey[DB]{}, txLockKey{}, strictTxKey{}, subtransactionsKey{}, readOnlyKey{}} {
+		if ctx.Value(key) != nil {
+			ctx = context.WithValue(ctx, key, nil)
+		}
+	}
+
+	return ctx
+}
+
+type txLockKey struct{}
+
+func txLockToContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, txLockKey{}, &sync.Mutex{})
+}
+
+// TxLockFromContext returns the lock serializing the statements of the transaction of the context,
+// nil if the transactions are not concurrency safe.
+func TxLockFromContext(ctx context.Context) *sync.Mutex {
+	if lock, ok := ctx.Value(txLockKey{}).(*sync.Mutex); ok {
+		return lock
+	}
+
+	return nil
+}
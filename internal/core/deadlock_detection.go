@@ -0,0 +1,179 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DeadlockError describes a goroutine waiting for a connection of an exhausted pool while it's holding a transaction,
+// which can only be released by the goroutine itself.
+type DeadlockError struct {
+	Waited      time.Duration // How long the goroutine waited before the deadlock was reported.
+	HolderStack []byte        // The stack trace of the goroutine when it started the transaction it's holding.
+	WaiterStack []byte        // The stack trace of the goroutine when it started waiting for a connection.
+}
+
+func (e *DeadlockError) Error() string {
+	return fmt.Sprintf(
+		"possible connection pool deadlock: waited %s for a connection while holding a transaction\n\ntransaction started at:\n%s\nwaiting at:\n%s",
+		e.Waited, e.HolderStack, e.WaiterStack,
+	)
+}
+
+// DeadlockDetection configures the pool deadlock detection.
+type DeadlockDetection struct {
+	// Threshold is the duration after which a goroutine holding a transaction and waiting for a connection
+	// of an exhausted pool is considered deadlocked, it must be positive.
+	Threshold time.Duration
+
+	// OnDeadlock is called when a deadlock is detected, for example to log the diagnostic.
+	// The waiting call is then canceled with the [*DeadlockError] as the cause.
+	OnDeadlock func(*DeadlockError)
+}
+
+// SetDeadlockDetection enables the detection of the goroutines that wait for a connection of an exhausted pool
+// while holding a transaction.
+// It panics if [DeadlockDetection.Threshold] is not positive, as every wait would be reported.
+func (t *Transactor[DB, Tx]) SetDeadlockDetection(config DeadlockDetection) {
+	if config.Threshold <= 0 {
+		panic(fmt.Errorf("invalid deadlock detection: Threshold must be positive, got %s", config.Threshold))
+	}
+
+	t.deadlocks = &DeadlockDetector{
+		DeadlockDetection: config,
+		held:              make(map[uint64][][]byte),
+	}
+}
+
+// SetPoolExhausted sets the function reporting whether the connection pool is exhausted, which is required
+// for a deadlock to happen. It does nothing if the deadlock detection is disabled.
+func (t *Transactor[DB, Tx]) SetPoolExhausted(poolExhausted func() bool) {
+	if t.deadlocks != nil {
+		t.deadlocks.poolExhausted = poolExhausted
+	}
+}
+
+// DeadlockDetector returns the deadlock detector, nil if the deadlock detection is disabled.
+func (t *Transactor[DB, Tx]) DeadlockDetector() *DeadlockDetector {
+	return t.deadlocks
+}
+
+// DeadlockDetector detects the goroutines waiting for a connection of an exhausted pool while holding a transaction.
+type DeadlockDetector struct {
+	DeadlockDetection
+
+	poolExhausted func() bool
+
+	mu   sync.Mutex
+	held map[uint64][][]byte // Stack traces of the transactions held by each goroutine
+}
+
+// hold records that the current goroutine holds a transaction, until the returned function is called.
+// A nil detector records nothing.
+func (d *DeadlockDetector) hold() func() {
+	if d == nil {
+		return func() {}
+	}
+
+	id := goroutineID()
+	stack := debug.Stack()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.held[id] = append(d.held[id], stack)
+
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		d.held[id] = d.held[id][:len(d.held[id])-1]
+		if len(d.held[id]) == 0 {
+			delete(d.held, id)
+		}
+	}
+}
+
+// Watch returns a context canceled if the current goroutine holds a transaction and waits for too long on an exhausted pool.
+// The context must only be used to acquire a connection: the returned function must be called as soon as it's acquired,
+// and it cancels the context.
+// A nil detector doesn't watch anything.
+func (d *DeadlockDetector) Watch(ctx context.Context) (context.Context, func()) {
+	ctx, stop, cancel := d.watch(ctx)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// watch is like Watch, but the returned functions respectively stop the watch and cancel the context,
+// for the calls that keep using the context once the connection is acquired, such as the database/sql transactions.
+func (d *DeadlockDetector) watch(ctx context.Context) (context.Context, func(), func()) {
+	if d == nil {
+		return ctx, func() {}, func() {}
+	}
+
+	d.mu.Lock()
+	holding := d.held[goroutineID()]
+	d.mu.Unlock()
+	if len(holding) == 0 {
+		return ctx, func() {}, func() {}
+	}
+
+	waiterStack := debug.Stack()
+	start := time.Now()
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(d.Threshold, func() {
+		if !d.poolExhausted() {
+			return
+		}
+
+		err := &DeadlockError{
+			Waited:      time.Since(start),
+			HolderStack: holding[0],
+			WaiterStack: waiterStack,
+		}
+		if d.OnDeadlock != nil {
+			d.OnDeadlock(err)
+		}
+		cancel(err)
+	})
+
+	stop := func() {
+		timer.Stop()
+	}
+	return ctx, stop, func() {
+		cancel(nil)
+	}
+}
+
+// DeadlockCause returns the [*DeadlockError] that canceled the context, if any, instead of the context error.
+func DeadlockCause(ctx context.Context, err error) error {
+	var deadlockErr *DeadlockError
+	if err != nil && errors.As(context.Cause(ctx), &deadlockErr) {
+		return deadlockErr
+	}
+
+	return err
+}
+
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+
+	// The stack trace starts with "goroutine <id> [<status>]:"
+	fields := bytes.Fields(buf[:n])
+	if len(fields) < 2 {
+		return 0
+	}
+
+	id, _ := strconv.ParseUint(string(fields[1]), 10, 64)
+	return id
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedTxOptions is returned when transaction parameters can't be expressed with the [sql.TxOptions] of database/sql.
+var ErrUnsupportedTxOptions = errors.New("transaction options not supported by database/sql")
+
+// firebirdReadCommittedNoWait is the LevelReadCommittedNoWait isolation level of github.com/nakagami/firebirdsql,
+// beginning a READ COMMITTED RECORD_VERSION transaction with NO WAIT.
+const firebirdReadCommittedNoWait sql.IsolationLevel = 1000
+
+// FirebirdIsolation is the isolation level of a Firebird transaction.
+type FirebirdIsolation int
+
+const (
+	// FirebirdReadCommitted is READ COMMITTED RECORD_VERSION: reads the last committed version of the records.
+	FirebirdReadCommitted FirebirdIsolation = iota
+	// FirebirdReadCommittedNoRecordVersion is READ COMMITTED NO RECORD_VERSION: waits for the uncommitted versions of the records.
+	FirebirdReadCommittedNoRecordVersion
+	// FirebirdReadConsistency is READ COMMITTED READ CONSISTENCY, the statement-level snapshot of Firebird 4 and later.
+	FirebirdReadConsistency
+	// FirebirdSnapshot is SNAPSHOT: reads a snapshot of the database taken when the transaction begins.
+	FirebirdSnapshot
+	// FirebirdSnapshotTableStability is SNAPSHOT TABLE STABILITY: a snapshot with exclusive access to the tables it reads.
+	FirebirdSnapshotTableStability
+)
+
+// FirebirdTransaction configures the parameters of the Firebird transactions.
+type FirebirdTransaction struct {
+	Isolation FirebirdIsolation
+
+	// ReadOnly begins READ ONLY transactions instead of READ WRITE.
+	ReadOnly bool
+
+	// NoWait makes the lock conflicts fail immediately instead of waiting for the conflicting transaction (NO WAIT).
+	NoWait bool
+
+	// LockTimeout is the maximum time to wait on a lock conflict, rounded up to the second. Zero waits indefinitely.
+	// It can't be combined with NoWait.
+	LockTimeout time.Duration
+}
+
+// SetTransaction returns the SET TRANSACTION statement beginning a transaction with these parameters.
+func (t FirebirdTransaction) SetTransaction() (string, error) {
+	if t.NoWait && t.LockTimeout > 0 {
+		return "", errors.New("a lock timeout can't be combined with NO WAIT")
+	}
+
+	clauses := []string{"SET TRANSACTION"}
+
+	if t.ReadOnly {
+		clauses = append(clauses, "READ ONLY")
+	} else {
+		clauses = append(clauses, "READ WRITE")
+	}
+
+	if t.NoWait {
+		clauses = append(clauses, "NO WAIT")
+	} else {
+		clauses = append(clauses, "WAIT")
+		if t.LockTimeout > 0 {
+			clauses = append(clauses, "LOCK TIMEOUT "+strconv.Itoa(int((t.LockTimeout+time.Second-1)/time.Second)))
+		}
+	}
+
+	switch t.Isolation {
+	case FirebirdReadCommitted:
+		clauses = append(clauses, "ISOLATION LEVEL READ COMMITTED RECORD_VERSION")
+	case FirebirdReadCommittedNoRecordVersion:
+		clauses = append(clauses, "ISOLATION LEVEL READ COMMITTED NO RECORD_VERSION")
+	case FirebirdReadConsistency:
+		clauses = append(clauses, "ISOLATION LEVEL READ COMMITTED READ CONSISTENCY")
+	case FirebirdSnapshot:
+		clauses = append(clauses, "ISOLATION LEVEL SNAPSHOT")
+	case FirebirdSnapshotTableStability:
+		clauses = append(clauses, "ISOLATION LEVEL SNAPSHOT TABLE STABILITY")
+	default:
+		return "", fmt.Errorf("unknown isolation level %d", t.Isolation)
+	}
+
+	return strings.Join(clauses, " "), nil
+}
+
+// TxOptions returns the [sql.TxOptions] beginning a transaction with these parameters with github.com/nakagami/firebirdsql,
+// which builds the transaction parameter buffer from them. It supports READ COMMITTED RECORD_VERSION, with WAIT or
+// NO WAIT, and read-only or not, and the read-write SNAPSHOT and SNAPSHOT TABLE STABILITY with WAIT. The other parameters,
+// such as the lock timeouts, return [ErrUnsupportedTxOptions].
+func (t FirebirdTransaction) TxOptions() (*sql.TxOptions, error) {
+	statement, err := t.SetTransaction()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.LockTimeout > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedTxOptions, statement)
+	}
+
+	opts := &sql.TxOptions{ReadOnly: t.ReadOnly}
+	switch {
+	case t.Isolation == FirebirdReadCommitted && t.NoWait:
+		opts.Isolation = firebirdReadCommittedNoWait
+	case t.Isolation == FirebirdReadCommitted:
+		opts.Isolation = sql.LevelReadCommitted
+	case t.ReadOnly || t.NoWait: // The driver only begins the read-only transactions READ COMMITTED, and the others WAIT
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedTxOptions, statement)
+	case t.Isolation == FirebirdSnapshot:
+		opts.Isolation = sql.LevelRepeatableRead
+	case t.Isolation == FirebirdSnapshotTableStability:
+		opts.Isolation = sql.LevelSerializable
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedTxOptions, statement)
+	}
+
+	return opts, nil
+}
+
+// AsReadOnly returns the parameters of the read-only transactions, see [WithReadOnly].
+func (t FirebirdTransaction) AsReadOnly() FirebirdTransaction {
+	t.ReadOnly = true
+	return t
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrImplicitCommit is returned when a statement that implicitly commits the current transaction is executed within it.
+var ErrImplicitCommit = errors.New("statement implicitly commits the transaction")
+
+// ImplicitCommits lists the statements that implicitly commit the current transaction on a database.
+type ImplicitCommits struct {
+	Database   string   // The name of the database, used in the errors.
+	Statements []string // The leading keywords of the statements, for example "CREATE" or "LOCK TABLES".
+	Exceptions []string // The leading keywords of the statements matching Statements that don't commit, for example "CREATE TEMPORARY".
+
+	// ExecutableComments reads the content of the executable comments of MySQL and MariaDB, /*! ... */ and /*M! ... */,
+	// optionally followed by a version, as part of the statement, since the database executes it.
+	ExecutableComments bool
+}
+
+// MySQLImplicitCommits returns the statements implicitly committing the transaction on MySQL and MariaDB.
+func MySQLImplicitCommits() *ImplicitCommits {
+	return &ImplicitCommits{
+		Database: "MySQL",
+		Statements: []string{
+			"ALTER", "CREATE", "DROP", "RENAME", "TRUNCATE",
+			"GRANT", "REVOKE", "SET PASSWORD", "INSTALL", "UNINSTALL",
+			"LOCK TABLE", "LOCK TABLES",
+			"ANALYZE", "CACHE INDEX", "CHECK TABLE", "FLUSH", "LOAD INDEX", "OPTIMIZE", "REPAIR", "RESET",
+			"BEGIN", "START TRANSACTION",
+		},
+		Exceptions:         []string{"CREATE TEMPORARY", "DROP TEMPORARY"},
+		ExecutableComments: true,
+	}
+}
+
+// OracleImplicitCommits returns the statements implicitly committing the transaction on Oracle.
+func OracleImplicitCommits() *ImplicitCommits {
+	return &ImplicitCommits{
+		Database: "Oracle",
+		Statements: []string{
+			"ALTER", "CREATE", "DROP", "RENAME", "TRUNCATE",
+			"GRANT", "REVOKE", "COMMENT", "ANALYZE", "AUDIT", "NOAUDIT", "PURGE", "FLASHBACK",
+			"ASSOCIATE STATISTICS", "DISASSOCIATE STATISTICS",
+		},
+		Exceptions: []string{"ALTER SESSION", "ALTER SYSTEM"},
+	}
+}
+
+// Check returns an error wrapping [ErrImplicitCommit] if the query implicitly commits the transaction.
+func (c *ImplicitCommits) Check(query string) error {
+	keywords := leadingKeywords(query, c.ExecutableComments)
+
+	for _, exception := range c.Exceptions {
+		if startsWithKeywords(keywords, exception) {
+			return nil
+		}
+	}
+
+	for _, statement := range c.Statements {
+		if startsWithKeywords(keywords, statement) {
+			return fmt.Errorf("%w: %s statements commit the current transaction on %s, they must run outside of it", ErrImplicitCommit, statement, c.Database)
+		}
+	}
+
+	return nil
+}
+
+// maxLeadingKeywords is the number of keywords read at the beginning of the statements,
+// enough for the longest statements and exceptions of the dialects.
+const maxLeadingKeywords = 2
+
+// leadingKeywords returns the first keywords of a query in upper case, separated by a space,
+// skipping the whitespaces, the comments and the opening parentheses.
+// With executableComments, the content of the executable comments is read instead of skipped.
+func leadingKeywords(query string, executableComments bool) string {
+	var keywords []string
+	for i := 0; i < len(query) && len(keywords) < maxLeadingKeywords; {
+		switch c := query[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '(':
+			i++
+
+		case executableComments && (strings.HasPrefix(query[i:], "/*!") || strings.HasPrefix(query[i:], "/*M!")):
+			i += strings.IndexByte(query[i:], '!') + 1
+			for i < len(query) && query[i] >= '0' && query[i] <= '9' {
+				i++ // The minimum version executing the comment
+			}
+
+		case executableComments && strings.HasPrefix(query[i:], "*/"):
+			i += 2 // The end of an executable comment
+
+		case strings.HasPrefix(query[i:], "--") || c == '#':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return strings.Join(keywords, " ")
+			}
+			i += end + 1
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return strings.Join(keywords, " ")
+			}
+			i += 2 + end + 2
+
+		case isKeywordChar(c):
+			start := i
+			for i < len(query) && isKeywordChar(query[i]) {
+				i++
+			}
+			keywords = append(keywords, strings.ToUpper(query[start:i]))
+
+		default:
+			return strings.Join(keywords, " ")
+		}
+	}
+
+	return strings.Join(keywords, " ")
+}
+
+func isKeywordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func startsWithKeywords(keywords, prefix string) bool {
+	return keywords == prefix || strings.HasPrefix(keywords, prefix+" ")
+}
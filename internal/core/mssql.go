@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrTransactionDoomed is returned when a nested transaction can't be rolled back because an error doomed the
+// outermost transaction, which can then only be rolled back entirely.
+var ErrTransactionDoomed = errors.New("transaction is doomed: it can only be rolled back entirely")
+
+// MSSQLStrategy is the [Strategy] of Microsoft SQL Server: it nests the transactions with savepoints, and checks
+// XACT_STATE() before rolling back to a savepoint. Some errors, or any error with SET XACT_ABORT ON, doom the
+// transaction (XACT_STATE() = -1) or roll it back entirely (XACT_STATE() = 0): rolling back to a savepoint is then
+// impossible, so the nested rollback fails with [ErrTransactionDoomed] and the outermost transaction becomes
+// rollback-only, see [RollbackOnlyChecker].
+// A MSSQLStrategy holds the state of a single outermost transaction.
+type MSSQLStrategy[Tx any] struct {
+	SavepointsStrategy[Tx]
+	QueryInt func(ctx context.Context, tx Tx, query string) (int, error)
+
+	doomed atomic.Bool
+}
+
+// NewMSSQLStrategy returns the strategy of an outermost transaction.
+func NewMSSQLStrategy[Tx any](exec func(ctx context.Context, tx Tx, query string) error, queryInt func(ctx context.Context, tx Tx, query string) (int, error)) *MSSQLStrategy[Tx] {
+	return &MSSQLStrategy[Tx]{
+		SavepointsStrategy: SavepointsStrategy[Tx]{Dialect: MSSQLDialect(), Exec: exec},
+		QueryInt:           queryInt,
+	}
+}
+
+func (s *MSSQLStrategy[Tx]) RollbackNested(ctx context.Context, tx NestedTx[Tx]) error {
+	state, err := s.QueryInt(ctx, tx.Tx, "SELECT XACT_STATE()")
+	if err != nil {
+		return fmt.Errorf("failed to get transaction state: %w", err)
+	}
+
+	if state != 1 {
+		s.doomed.Store(true)
+		return ErrTransactionDoomed
+	}
+
+	return s.SavepointsStrategy.RollbackNested(ctx, tx)
+}
+
+// RollbackOnly returns [ErrTransactionDoomed] if a nested rollback found the transaction doomed.
+func (s *MSSQLStrategy[Tx]) RollbackOnly() error {
+	if s.doomed.Load() {
+		return ErrTransactionDoomed
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// NestedTx is a nested transaction, passed to the hooks of a [Strategy].
+type NestedTx[Tx any] struct {
+	Tx        Tx     // The outermost transaction, on which the nested transactions run.
+	Depth     int    // The depth of the nested transaction: 1 when nested in the outermost transaction, 2 when nested in a nested transaction, etc.
+	Savepoint string // A savepoint name for the nested transaction, unique within the outermost transaction.
+}
+
+// Strategy implements the nested transactions of a database.
+type Strategy[Tx any] interface {
+	// BeginNested begins the nested transaction, typically by creating its savepoint.
+	BeginNested(ctx context.Context, tx NestedTx[Tx]) error
+
+	// CommitNested commits the nested transaction, typically by releasing its savepoint.
+	// Its changes are only persisted when the outermost transaction commits.
+	CommitNested(ctx context.Context, tx NestedTx[Tx]) error
+
+	// RollbackNested rolls back the changes of the nested transaction, typically by rolling back to its savepoint.
+	// The outermost transaction must stay usable.
+	RollbackNested(ctx context.Context, tx NestedTx[Tx]) error
+}
+
+// savepointNameLimiter is implemented by the strategies limiting the length of the savepoint names.
+type savepointNameLimiter interface {
+	MaxSavepointNameLength() int
+}
+
+// SavepointDialect is the SQL used by a database to nest transactions with savepoints.
+type SavepointDialect struct {
+	Savepoint        string // The statement creating a savepoint, followed by its name.
+	SavepointOptions string // The options of the statement creating a savepoint, following its name.
+	Release          string // The statement releasing a savepoint, followed by its name. Empty if savepoints can't be released.
+	RollbackTo       string // The statement rolling back to a savepoint, followed by its name.
+	MaxNameLength    int    // The maximum length of the savepoint names.
+}
+
+// SavepointsDialect returns the savepoints SQL of PostgreSQL, MySQL, MariaDB, and SQLite.
+func SavepointsDialect() *SavepointDialect {
+	return &SavepointDialect{
+		Savepoint:     "SAVEPOINT ",
+		Release:       "RELEASE SAVEPOINT ",
+		RollbackTo:    "ROLLBACK TO SAVEPOINT ",
+		MaxNameLength: 63, // PostgreSQL limits identifiers to 63 characters, MySQL and MariaDB to 64
+	}
+}
+
+// OracleDialect returns the savepoints SQL of Oracle.
+func OracleDialect() *SavepointDialect {
+	return &SavepointDialect{
+		Savepoint:     "SAVEPOINT ",
+		RollbackTo:    "ROLLBACK TO SAVEPOINT ",
+		MaxNameLength: 30, // Oracle limits identifiers to 30 characters before 12.2
+	}
+}
+
+// MSSQLDialect returns the savepoints SQL of Microsoft SQL Server.
+func MSSQLDialect() *SavepointDialect {
+	return &SavepointDialect{
+		Savepoint:     "SAVE TRANSACTION ",
+		RollbackTo:    "ROLLBACK TRANSACTION ",
+		MaxNameLength: 32, // SQL Server limits the savepoint names to 32 characters
+	}
+}
+
+// FirebirdDialect returns the savepoints SQL of Firebird.
+func FirebirdDialect() *SavepointDialect {
+	return &SavepointDialect{
+		Savepoint:     "SAVEPOINT ",
+		Release:       "RELEASE SAVEPOINT ",
+		RollbackTo:    "ROLLBACK TO SAVEPOINT ",
+		MaxNameLength: 31, // Firebird limits identifiers to 31 characters before 4.0
+	}
+}
+
+// DB2Dialect returns the savepoints SQL of IBM Db2.
+func DB2Dialect() *SavepointDialect {
+	return &SavepointDialect{
+		Savepoint:        "SAVEPOINT ",
+		SavepointOptions: " ON ROLLBACK RETAIN CURSORS", // Required by Db2, the cursors opened before the savepoint stay open
+		Release:          "RELEASE SAVEPOINT ",
+		RollbackTo:       "ROLLBACK TO SAVEPOINT ",
+		MaxNameLength:    128, // Db2 limits identifiers to 128 characters
+	}
+}
+
+// SavepointsStrategy is a [Strategy] nesting the transactions with the savepoints of a dialect.
+type SavepointsStrategy[Tx any] struct {
+	Dialect *SavepointDialect
+	Exec    func(ctx context.Context, tx Tx, query string) error
+}
+
+func (s SavepointsStrategy[Tx]) BeginNested(ctx context.Context, tx NestedTx[Tx]) error {
+	return s.Exec(ctx, tx.Tx, s.Dialect.Savepoint+tx.Savepoint+s.Dialect.SavepointOptions)
+}
+
+func (s SavepointsStrategy[Tx]) CommitNested(ctx context.Context, tx NestedTx[Tx]) error {
+	if s.Dialect.Release == "" {
+		return nil // Savepoints can't be released
+	}
+
+	return s.Exec(ctx, tx.Tx, s.Dialect.Release+tx.Savepoint)
+}
+
+func (s SavepointsStrategy[Tx]) RollbackNested(ctx context.Context, tx NestedTx[Tx]) error {
+	return s.Exec(ctx, tx.Tx, s.Dialect.RollbackTo+tx.Savepoint)
+}
+
+func (s SavepointsStrategy[Tx]) MaxSavepointNameLength() int {
+	return s.Dialect.MaxNameLength
+}
+
+// NestedTransaction is a transaction nested with a [Strategy], or the outermost transaction at depth 0.
+// The drivers wrap it in their transaction type.
+type NestedTransaction[Tx any] struct {
+	strategy  Strategy[Tx]
+	tx        Tx
+	errTxDone error
+
+	names SavepointNames
+	depth int
+	done  atomic.Bool
+}
+
+// NewNestedTransaction returns the outermost transaction, using the strategy to create its nested transactions.
+// errTxDone is returned when completing a nested transaction twice.
+func NewNestedTransaction[Tx any](strategy Strategy[Tx], tx Tx, errTxDone error) *NestedTransaction[Tx] {
+	return &NestedTransaction[Tx]{strategy: strategy, tx: tx, errTxDone: errTxDone}
+}
+
+// Nest returns the nested transaction begun by [NestedTransaction.Begin].
+func (t *NestedTransaction[Tx]) Nest() *NestedTransaction[Tx] {
+	return &NestedTransaction[Tx]{
+		strategy:  t.strategy,
+		tx:        t.tx,
+		errTxDone: t.errTxDone,
+		names:     t.names,
+		depth:     t.depth + 1,
+	}
+}
+
+// Begin begins the next nested transaction, counting its savepoint against the subtransaction budget of the context.
+// It fails with [ErrInvalidSavepointName] if the name of its savepoint exceeds the limit of the strategy.
+func (t *NestedTransaction[Tx]) Begin(ctx context.Context) error {
+	name := t.names.Name(t.depth + 1)
+	if maxLength := t.MaxSavepointNameLength(); len(name) > maxLength {
+		return fmt.Errorf("failed to create savepoint: %w: %q exceeds the limit of %d characters", ErrInvalidSavepointName, name, maxLength)
+	}
+
+	if !t.SupportsSavepoints() {
+		return t.Savepoint(ctx, name)
+	}
+
+	subtransactions := subtransactionsFromContext(ctx)
+	flatten, err := subtransactions.spend(ctx)
+	if err != nil {
+		return err
+	}
+	if flatten {
+		return errSubtransactionFlattened
+	}
+
+	if err := t.Savepoint(ctx, name); err != nil {
+		subtransactions.refund()
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	return nil
+}
+
+// Commit commits the nested transaction.
+func (t *NestedTransaction[Tx]) Commit(ctx context.Context) error {
+	if !t.done.CompareAndSwap(false, true) {
+		return t.errTxDone
+	}
+
+	if err := t.strategy.CommitNested(ctx, t.nestedTx(t.depth, t.names.Name(t.depth))); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+
+	return nil
+}
+
+// Rollback rolls back the nested transaction.
+func (t *NestedTransaction[Tx]) Rollback(ctx context.Context) error {
+	if !t.done.CompareAndSwap(false, true) {
+		return t.errTxDone
+	}
+
+	if err := t.strategy.RollbackNested(ctx, t.nestedTx(t.depth, t.names.Name(t.depth))); err != nil {
+		return fmt.Errorf("failed to rollback to savepoint: %w", err)
+	}
+
+	return nil
+}
+
+// Savepoint begins a nested transaction with the given savepoint name.
+func (t *NestedTransaction[Tx]) Savepoint(ctx context.Context, name string) error {
+	return t.strategy.BeginNested(ctx, t.nestedTx(t.depth+1, name)) //nolint:wrapcheck
+}
+
+// ReleaseSavepoint commits the nested transaction begun by [NestedTransaction.Savepoint].
+func (t *NestedTransaction[Tx]) ReleaseSavepoint(ctx context.Context, name string) error {
+	return t.strategy.CommitNested(ctx, t.nestedTx(t.depth+1, name)) //nolint:wrapcheck
+}
+
+// RollbackToSavepoint rolls back the nested transaction begun by [NestedTransaction.Savepoint].
+func (t *NestedTransaction[Tx]) RollbackToSavepoint(ctx context.Context, name string) error {
+	return t.strategy.RollbackNested(ctx, t.nestedTx(t.depth+1, name)) //nolint:wrapcheck
+}
+
+// SupportsSavepoints returns false if the strategy flattens the nested transactions without savepoints, see [RollbackOnlyStrategy].
+func (t *NestedTransaction[Tx]) SupportsSavepoints() bool {
+	_, flattened := t.strategy.(*RollbackOnlyStrategy[Tx])
+	return !flattened
+}
+
+func (t *NestedTransaction[Tx]) nestedTx(depth int, name string) NestedTx[Tx] {
+	return NestedTx[Tx]{Tx: t.tx, Depth: depth, Savepoint: name}
+}
+
+// SetSavepointNames sets the names of the savepoints, on the outermost transaction.
+func (t *NestedTransaction[Tx]) SetSavepointNames(names SavepointNames) {
+	t.names = names
+}
+
+// MaxSavepointNameLength returns the maximum length of the savepoint names supported by the strategy.
+func (t *NestedTransaction[Tx]) MaxSavepointNameLength() int {
+	return maxSavepointNameLength(t.strategy)
+}
+
+// maxSavepointNameLength returns the maximum length of the savepoint names supported by a strategy or a savepointer.
+func maxSavepointNameLength(limiter any) int {
+	if limiter, ok := limiter.(savepointNameLimiter); ok {
+		return limiter.MaxSavepointNameLength()
+	}
+
+	return math.MaxInt
+}
+
+// CurrentSavepoint returns the name of the savepoint of the nested transaction, false for the outermost transaction.
+func (t *NestedTransaction[Tx]) CurrentSavepoint() (string, bool) {
+	if t.depth == 0 {
+		return "", false
+	}
+
+	return t.names.Name(t.depth), true
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"errors"
+	"strings"
+)
+
+// maxOracleTransactionNameLength is the maximum length of the name of an Oracle transaction, in bytes.
+const maxOracleTransactionNameLength = 255
+
+// OracleTransaction configures the SET TRANSACTION statement beginning the Oracle transactions.
+// Oracle requires it to be the first statement of the transaction, and accepts a single option besides the name.
+type OracleTransaction struct {
+	// ReadOnly begins READ ONLY transactions: they read a snapshot of the database taken when they begin,
+	// and fail to modify it.
+	ReadOnly bool
+
+	// Serializable begins the transactions with the SERIALIZABLE isolation level instead of READ COMMITTED.
+	// It can't be combined with ReadOnly.
+	Serializable bool
+
+	// Name names the transactions, as shown in the NAME column of V$TRANSACTION.
+	Name string
+}
+
+// SetTransaction returns the SET TRANSACTION statement beginning a transaction with these options,
+// empty if they're all unset.
+func (t OracleTransaction) SetTransaction() (string, error) {
+	if t.ReadOnly && t.Serializable {
+		return "", errors.New("a read-only transaction can't set its isolation level")
+	}
+	if len(t.Name) > maxOracleTransactionNameLength {
+		return "", errors.New("the transaction name can't be longer than 255 bytes")
+	}
+
+	clauses := []string{"SET TRANSACTION"}
+
+	switch {
+	case t.ReadOnly:
+		clauses = append(clauses, "READ ONLY")
+	case t.Serializable:
+		clauses = append(clauses, "ISOLATION LEVEL SERIALIZABLE")
+	}
+
+	if t.Name != "" {
+		clauses = append(clauses, "NAME '"+strings.ReplaceAll(t.Name, "'", "''")+"'")
+	}
+
+	if len(clauses) == 1 {
+		return "", nil
+	}
+
+	return strings.Join(clauses, " "), nil
+}
+
+// AsReadOnly returns the options of the read-only transactions, see [WithReadOnly].
+func (t OracleTransaction) AsReadOnly() OracleTransaction {
+	t.ReadOnly = true
+	t.Serializable = false
+	return t
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// ProbeReport is the report of the capabilities of a database with a nested transactions strategy.
+type ProbeReport struct {
+	Checks []ProbeCheck `json:"checks"`
+}
+
+// Supported reports whether all the checks required by the nested transactions strategy passed,
+// regardless of the optional checks.
+func (r ProbeReport) Supported() bool {
+	for _, check := range r.Checks {
+		if !check.Optional && !check.Supported() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ProbeCheck is the outcome of a capability check.
+type ProbeCheck struct {
+	Name     string // For example "savepoint" or "isolation level Serializable".
+	Err      error  // The reason why the check failed, nil if it passed.
+	Optional bool   // Whether the check is informational, such as the isolation levels, rather than required by the strategy.
+}
+
+// The optional checks only tell whether the driver accepts an option, not whether the database applies it: SQLite, for
+// example, accepts every isolation level and runs all the transactions as Serializable.
+
+// Supported reports whether the check passed.
+func (c ProbeCheck) Supported() bool {
+	return c.Err == nil
+}
+
+func (c ProbeCheck) MarshalJSON() ([]byte, error) {
+	check := struct {
+		Name      string `json:"name"`
+		Supported bool   `json:"supported"`
+		Optional  bool   `json:"optional"`
+		Error     string `json:"error,omitempty"`
+	}{
+		Name:      c.Name,
+		Supported: c.Supported(),
+		Optional:  c.Optional,
+	}
+	if c.Err != nil {
+		check.Error = c.Err.Error()
+	}
+
+	return json.Marshal(check) //nolint:wrapcheck
+}
+
+// ProbeSavepoints checks the creation, release and rollback of a savepoint with the nested transactions begun by
+// beginNested, within an outermost transaction begun by the caller.
+func ProbeSavepoints(ctx context.Context, beginNested func(context.Context) (Completer, error)) []ProbeCheck {
+	savepoint := ProbeCheck{Name: "savepoint"}
+	release := ProbeCheck{Name: "release savepoint"}
+	rollback := ProbeCheck{Name: "rollback to savepoint"}
+
+	nestedTx, err := beginNested(ctx)
+	if err != nil {
+		savepoint.Err = err
+		release.Err = fmt.Errorf("no savepoint to release: %w", err)
+		rollback.Err = fmt.Errorf("no savepoint to rollback to: %w", err)
+		return []ProbeCheck{savepoint, release, rollback}
+	}
+	release.Err = nestedTx.Commit(ctx)
+
+	if nestedTx, err = beginNested(ctx); err != nil {
+		rollback.Err = fmt.Errorf("no savepoint to rollback to: %w", err)
+		return []ProbeCheck{savepoint, release, rollback}
+	}
+	rollback.Err = nestedTx.Rollback(ctx)
+
+	return []ProbeCheck{savepoint, release, rollback}
+}
+
+// ProbeTxOptions checks the read-only transactions and the isolation levels accepted by a database/sql driver.
+// The checks are optional, since the nested transactions don't depend on them, and a passing check doesn't mean that the
+// database runs the transaction with this isolation level.
+func ProbeTxOptions(ctx context.Context, db *sql.DB) []ProbeCheck {
+	checks := []ProbeCheck{{
+		Name:     "read only",
+		Err:      probeTxOptions(ctx, db, &sql.TxOptions{ReadOnly: true}),
+		Optional: true,
+	}}
+
+	for _, level := range []sql.IsolationLevel{
+		sql.LevelReadUncommitted,
+		sql.LevelReadCommitted,
+		sql.LevelRepeatableRead,
+		sql.LevelSnapshot,
+		sql.LevelSerializable,
+	} {
+		checks = append(checks, ProbeCheck{
+			Name:     "isolation level " + level.String(),
+			Err:      probeTxOptions(ctx, db, &sql.TxOptions{Isolation: level}),
+			Optional: true,
+		})
+	}
+
+	return checks
+}
+
+// probeTxOptions checks that a transaction can be started with the given options.
+func probeTxOptions(ctx context.Context, db *sql.DB, opts *sql.TxOptions) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return tx.Rollback() //nolint:wrapcheck
+}
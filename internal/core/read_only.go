@@ -0,0 +1,16 @@
+package core
+
+import "context"
+
+type readOnlyKey struct{}
+
+// WithReadOnly returns a context in which the outermost transactions are read-only.
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// IsReadOnly reports whether the outermost transactions of the context are read-only.
+func IsReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey{}).(bool)
+	return readOnly
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// maxRestarts is the maximum number of times an outermost transaction is restarted before giving up.
+const maxRestarts = 50
+
+// Starter runs the first statements of an outermost transaction, before its callback.
+// It's implemented by the [Completer] of the outermost transactions that must be prepared once begun.
+type Starter interface {
+	// Start runs the first statements of the transaction.
+	Start(ctx context.Context) error
+}
+
+// Restarter restarts an outermost transaction within the same transaction when its callback fails with a retryable error.
+// It's implemented by the [Completer] of the outermost transactions of the databases with a client-side retry protocol.
+type Restarter interface {
+	// Start marks the beginning of the transaction, to restart it from.
+	Starter
+
+	// Release releases the mark before the transaction commits. It can fail with a retryable error.
+	Release(ctx context.Context) error
+
+	// Restart discards the changes made since the mark, so that the callback can run again.
+	Restart(ctx context.Context) error
+
+	// IsRetryable reports whether the transaction must be restarted after the error.
+	IsRetryable(err error) bool
+}
+
+// run executes the callback of a transaction, starting it first if the outermost transaction is a [Starter], and
+// restarting it if it's a [Restarter].
+func (t *Transactor[DB, Tx]) run(ctx, txCtx context.Context, tx Completer, outermost bool, txFunc func(context.Context) error) error {
+	if !outermost {
+		return txFunc(txCtx)
+	}
+
+	if starter, ok := tx.(Starter); ok {
+		if err := starter.Start(ctx); err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+	}
+
+	restarter, ok := tx.(Restarter)
+	if !ok {
+		return txFunc(txCtx)
+	}
+
+	for restarts := 0; ; restarts++ {
+		err := txFunc(txCtx)
+		if err == nil {
+			if err = restarter.Release(ctx); err == nil {
+				return nil
+			}
+			err = fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		if !restarter.IsRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if restarts == maxRestarts {
+			return fmt.Errorf("transaction restarted %d times: %w", restarts, err)
+		}
+
+		if restartErr := restarter.Restart(ctx); restartErr != nil {
+			return fmt.Errorf("failed to restart transaction: %w", errors.Join(restartErr, err))
+		}
+
+		// The savepoints of the previous run are discarded by the restart
+		subtransactionsFromContext(ctx).restart()
+	}
+}
+
+// StartWith returns the completer of an outermost transaction that runs start once the transaction is begun, and again
+// after every restart if it's a [Restarter]. start runs after the Start and Restart of the Restarter, so that the
+// first statement of the transaction is the one expected by the retry protocol, and the changes of start that are
+// discarded by a restart are made again.
+func StartWith(tx Completer, start func(ctx context.Context) error) Completer {
+	if restarter, ok := tx.(Restarter); ok {
+		return restartingStarter{Completer: tx, Restarter: restarter, start: start}
+	}
+
+	return starter{Completer: tx, start: start}
+}
+
+type starter struct {
+	Completer
+	start func(ctx context.Context) error
+}
+
+func (s starter) Start(ctx context.Context) error {
+	if starter, ok := s.Completer.(Starter); ok {
+		if err := starter.Start(ctx); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	return s.start(ctx)
+}
+
+type restartingStarter struct {
+	Completer
+	Restarter
+	start func(ctx context.Context) error
+}
+
+func (s restartingStarter) Start(ctx context.Context) error {
+	if err := s.Restarter.Start(ctx); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return s.start(ctx)
+}
+
+func (s restartingStarter) Restart(ctx context.Context) error {
+	if err := s.Restarter.Restart(ctx); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return s.start(ctx)
+}
+
+// CockroachRestart is the [Restarter] implementing the client-side retry protocol of CockroachDB,
+// with the cockroach_restart savepoint.
+type CockroachRestart[Tx any] struct {
+	Tx   Tx
+	Exec func(ctx context.Context, tx Tx, query string) error
+}
+
+func (r CockroachRestart[Tx]) Start(ctx context.Context) error {
+	return r.Exec(ctx, r.Tx, "SAVEPOINT cockroach_restart")
+}
+
+func (r CockroachRestart[Tx]) Release(ctx context.Context) error {
+	return r.Exec(ctx, r.Tx, "RELEASE SAVEPOINT cockroach_restart")
+}
+
+func (r CockroachRestart[Tx]) Restart(ctx context.Context) error {
+	return r.Exec(ctx, r.Tx, "ROLLBACK TO SAVEPOINT cockroach_restart")
+}
+
+func (r CockroachRestart[Tx]) IsRetryable(err error) bool {
+	return IsSerializationFailure(err)
+}
+
+// IsSerializationFailure reports whether the error is a serialization failure, with the SQLSTATE 40001.
+// It supports the errors of the drivers exposing their SQLSTATE with a SQLState method, such as pgx and lib/pq.
+func IsSerializationFailure(err error) bool {
+	var sqlStateErr interface{ SQLState() string }
+	return errors.As(err, &sqlStateErr) && sqlStateErr.SQLState() == "40001"
+}
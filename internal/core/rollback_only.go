@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrRollbackOnly is returned when committing an outermost transaction after one of its flattened nested transactions
+// was rolled back. The outermost transaction is rolled back instead.
+var ErrRollbackOnly = errors.New("transaction is rollback-only: one of its nested transactions was rolled back")
+
+// RollbackOnlyChecker is implemented by the strategies that can make their outermost transaction rollback-only.
+// RollbackOnly returns why the outermost transaction must be rolled back instead of committed, nil if it can be committed.
+type RollbackOnlyChecker interface {
+	RollbackOnly() error
+}
+
+// RollbackOnlyStrategy is a [Strategy] for the databases without savepoints: the nested transactions are flattened
+// into the outermost transaction, and rolling back one of them marks the outermost transaction as rollback-only,
+// since its changes can't be undone separately.
+// A RollbackOnlyStrategy holds the state of a single outermost transaction.
+type RollbackOnlyStrategy[Tx any] struct {
+	rollbackOnly atomic.Bool
+}
+
+func (s *RollbackOnlyStrategy[Tx]) BeginNested(context.Context, NestedTx[Tx]) error {
+	return nil
+}
+
+func (s *RollbackOnlyStrategy[Tx]) CommitNested(context.Context, NestedTx[Tx]) error {
+	return nil
+}
+
+func (s *RollbackOnlyStrategy[Tx]) RollbackNested(context.Context, NestedTx[Tx]) error {
+	s.rollbackOnly.Store(true)
+	return nil
+}
+
+// RollbackOnly returns [ErrRollbackOnly] if one of the nested transactions was rolled back.
+func (s *RollbackOnlyStrategy[Tx]) RollbackOnly() error {
+	if s.rollbackOnly.Load() {
+		return ErrRollbackOnly
+	}
+
+	return nil
+}
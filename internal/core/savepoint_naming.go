@@ -0,0 +1,55 @@
+package core
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+)
+
+const (
+	// DefaultSavepointPrefix is the prefix of the savepoint names of the nested transactions.
+	DefaultSavepointPrefix = "sp_"
+
+	savepointIDAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	savepointIDLength   = 8
+)
+
+var savepointPrefixRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// SavepointNames names the savepoints of the nested transactions of an outermost transaction.
+type SavepointNames struct {
+	prefix string
+	id     string
+}
+
+// NewSavepointNames returns the savepoint names of a new outermost transaction, with an id unique to it.
+func NewSavepointNames(prefix string) SavepointNames {
+	id := make([]byte, savepointIDLength)
+	for i := range id {
+		id[i] = savepointIDAlphabet[rand.IntN(len(savepointIDAlphabet))] //nolint:gosec // The names don't need to be unpredictable
+	}
+
+	return SavepointNames{prefix: prefix, id: string(id)}
+}
+
+// Name returns the name of the savepoint of the nested transaction at the given depth.
+func (n SavepointNames) Name(depth int) string {
+	return n.prefix + n.id + "_" + strconv.Itoa(depth)
+}
+
+// Statement returns the name of the implicit savepoints of the statements, which run one at a time on a transaction.
+// It's the name at depth 0, which is never used by the nested transactions.
+func (n SavepointNames) Statement() string {
+	return n.Name(0)
+}
+
+// ValidateSavepointPrefix checks that the prefix generates valid savepoint names.
+// Their length is checked against the limit of the strategy when the nested transactions begin.
+func ValidateSavepointPrefix(prefix string) error {
+	if !savepointPrefixRegexp.MatchString(prefix) {
+		return fmt.Errorf("%w: prefix %q", ErrInvalidSavepointName, prefix)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var (
+	// ErrNoTransaction is returned when the context is not within a transaction.
+	ErrNoTransaction = errors.New("no transaction in the context")
+
+	// ErrInvalidSavepointName is returned when a savepoint name is not a valid SQL identifier.
+	ErrInvalidSavepointName = errors.New("invalid savepoint name")
+
+	// ErrSavepointsNotSupported is returned when using a savepoint with a nested transactions strategy that doesn't support them.
+	ErrSavepointsNotSupported = errors.New("savepoints are not supported by the nested transactions strategy")
+
+	savepointNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// SavepointError is returned when a savepoint can't be created, released, or rolled back to.
+// The transaction should then be considered unusable.
+type SavepointError struct {
+	Name  string // The name of the savepoint.
+	Op    string // The failed operation: "create", "release" or "rollback to".
+	Err   error  // The error of the operation.
+	Cause error  // The error of the callback that triggered the rollback, if any.
+}
+
+func (e *SavepointError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("failed to %s savepoint %s after %v: %v", e.Op, e.Name, e.Cause, e.Err)
+	}
+
+	return fmt.Sprintf("failed to %s savepoint %s: %v", e.Op, e.Name, e.Err)
+}
+
+func (e *SavepointError) Unwrap() []error {
+	return []error{e.Err, e.Cause}
+}
+
+// Savepointer creates, releases, and rolls back to the savepoints of a transaction.
+type Savepointer interface {
+	Savepoint(ctx context.Context, name string) error
+	ReleaseSavepoint(ctx context.Context, name string) error
+	RollbackToSavepoint(ctx context.Context, name string) error
+}
+
+// AsSavepointer returns the [Savepointer] of a transaction, or false if it can't create savepoints: it doesn't
+// implement Savepointer, or its nested transactions strategy flattens them without savepoints, see [RollbackOnlyStrategy].
+func AsSavepointer(tx any) (Savepointer, bool) {
+	sp, ok := tx.(Savepointer)
+	if !ok {
+		return nil, false
+	}
+
+	if nested, ok := sp.(interface{ SupportsSavepoints() bool }); ok && !nested.SupportsSavepoints() {
+		return nil, false
+	}
+
+	return sp, true
+}
+
+// ValidateSavepointName checks that a savepoint name is a valid SQL identifier.
+func ValidateSavepointName(name string) error {
+	if !savepointNameRegexp.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidSavepointName, name)
+	}
+
+	return nil
+}
+
+// RunInSavepoint executes the function within a savepoint, and returns its error separately from the error of the savepoint.
+// It fails with [ErrInvalidSavepointName] before creating the savepoint if its name exceeds the limit of the nested
+// transactions strategy of the savepointer. If the function fails, its changes are rolled back to the savepoint.
+// The savepoint is counted against the subtransaction budget of the context: past it, with [SubtransactionsFlatten],
+// the function runs without a savepoint and its failure makes the outermost transaction rollback-only.
+func RunInSavepoint(ctx context.Context, sp Savepointer, name string, fn func(context.Context) error) (error, error) {
+	if maxLength := maxSavepointNameLength(sp); len(name) > maxLength {
+		return nil, fmt.Errorf("%w: %q exceeds the limit of %d characters", ErrInvalidSavepointName, name, maxLength)
+	}
+
+	subtransactions := subtransactionsFromContext(ctx)
+	flatten, err := subtransactions.spend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if flatten {
+		return subtransactions.runFlattened(ctx, fn), nil
+	}
+
+	if err := sp.Savepoint(ctx, name); err != nil {
+		subtransactions.refund()
+		return nil, &SavepointError{Name: name, Op: "create", Err: err}
+	}
+
+	if fnErr := fn(ctx); fnErr != nil {
+		if err := sp.RollbackToSavepoint(ctx, name); err != nil {
+			return nil, &SavepointError{Name: name, Op: "rollback to", Err: err, Cause: fnErr}
+		}
+
+		return fnErr, nil
+	}
+
+	if err := sp.ReleaseSavepoint(ctx, name); err != nil {
+		return nil, &SavepointError{Name: name, Op: "release", Err: err}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SessionSettings returns the PostgreSQL settings to apply to the outermost transactions begun within a context,
+// for example the identity of the caller checked by the row-level security policies with current_setting.
+type SessionSettings func(ctx context.Context) map[string]string
+
+// SetConfig returns the statement applying the settings with set_config to the current transaction only,
+// like SET LOCAL, and its arguments. It returns an empty statement if there are no settings.
+func SetConfig(settings map[string]string) (string, []any) {
+	if len(settings) == 0 {
+		return "", nil
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	slices.Sort(names) // Deterministic statements
+
+	var query strings.Builder
+	args := make([]any, 0, 2*len(names))
+	query.WriteString("SELECT ")
+	for i, name := range names {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("set_config($" + strconv.Itoa(2*i+1) + ", $" + strconv.Itoa(2*i+2) + ", true)")
+		args = append(args, name, settings[name])
+	}
+
+	return query.String(), args
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrShuttingDown is returned when starting a new transaction on a transactor that is shutting down.
+// It's also the cause of the context cancellation of the transactions aborted by [Transactor.Shutdown].
+var ErrShuttingDown = errors.New("transactor is shutting down")
+
+// inFlightTransactions keeps track of the outermost transactions currently running.
+type inFlightTransactions struct {
+	mu           sync.Mutex
+	shuttingDown bool
+	nextID       uint64
+	cancels      map[uint64]context.CancelCauseFunc
+	drained      chan struct{}
+}
+
+// start registers a new outermost transaction.
+// It returns the context to use for the transaction, and a function to call once the transaction is done.
+func (t *inFlightTransactions) start(ctx context.Context) (context.Context, func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.shuttingDown {
+		return nil, nil, ErrShuttingDown
+	}
+
+	if t.cancels == nil {
+		t.cancels = make(map[uint64]context.CancelCauseFunc)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	id := t.nextID
+	t.nextID++
+	t.cancels[id] = cancel
+
+	return ctx, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		cancel(nil)
+		delete(t.cancels, id)
+		if len(t.cancels) == 0 && t.drained != nil {
+			close(t.drained)
+			t.drained = nil
+		}
+	}, nil
+}
+
+// drain refuses new transactions and waits for the running ones to finish.
+// When the context expires, the remaining transactions are canceled and their count is returned right away:
+// they're rolled back by their WithinTransaction once they notice the cancellation.
+func (t *inFlightTransactions) drain(ctx context.Context) (int, error) {
+	t.mu.Lock()
+	t.shuttingDown = true
+	if len(t.cancels) == 0 {
+		t.mu.Unlock()
+		return 0, nil
+	}
+	if t.drained == nil {
+		t.drained = make(chan struct{})
+	}
+	drained := t.drained
+	t.mu.Unlock()
+
+	select {
+	case <-drained:
+		return 0, nil
+
+	case <-ctx.Done():
+		t.mu.Lock()
+		aborted := len(t.cancels)
+		for _, cancel := range t.cancels {
+			cancel(ErrShuttingDown)
+		}
+		t.mu.Unlock()
+
+		if aborted == 0 {
+			return 0, nil
+		}
+
+		return aborted, fmt.Errorf("failed to drain transactions: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,234 @@
+// Package strategytest implements the conformance test kit for the nested transactions strategies shared by the
+// strategytest packages of stdlib and sqlx.
+package strategytest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// Table is the table created by [Run] to check the changes made by the transactions.
+const Table = "transactor_strategytest"
+
+var errRollback = errors.New("strategytest: rollback")
+
+// Transactor is the transactor under test, using the strategy to nest its transactions.
+type Transactor struct {
+	WithinTransaction func(ctx context.Context, fn func(context.Context) error) error
+	WithinSavepoint   func(ctx context.Context, name string, fn func(context.Context) error) error
+
+	// Exec executes a statement with the DB handler returned by the DB getter for the context.
+	Exec func(ctx context.Context, query string) error
+}
+
+// Run checks that the strategy of the transactor implements the nested transactions on the database, see the Run
+// function of the strategytest packages of stdlib and sqlx.
+func Run(t *testing.T, db *sql.DB, transactor Transactor) {
+	t.Helper()
+
+	ctx := context.Background()
+	_, _ = db.ExecContext(ctx, "DROP TABLE "+Table)
+	if _, err := db.ExecContext(ctx, "CREATE TABLE "+Table+" (id INTEGER)"); err != nil {
+		t.Fatalf("failed to create table %s: %v", Table, err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(context.Background(), "DROP TABLE "+Table)
+	})
+
+	insert := func(ctx context.Context, id int) error {
+		return transactor.Exec(ctx, "INSERT INTO "+Table+" (id) VALUES ("+strconv.Itoa(id)+")")
+	}
+	nested := func(ctx context.Context, fn func(context.Context) error) error {
+		return transactor.WithinTransaction(ctx, fn)
+	}
+
+	tests := []struct {
+		name    string
+		run     func(ctx context.Context) error
+		wantErr error
+		wantIDs []int
+	}{
+		{
+			name: "it should keep the changes of a committed nested transaction",
+			run: func(ctx context.Context) error {
+				if err := insert(ctx, 1); err != nil {
+					return err
+				}
+
+				return nested(ctx, func(ctx context.Context) error {
+					return insert(ctx, 2)
+				})
+			},
+			wantIDs: []int{1, 2},
+		},
+		{
+			name: "it should discard the changes of a rolled back nested transaction and keep the transaction usable",
+			run: func(ctx context.Context) error {
+				if err := insert(ctx, 1); err != nil {
+					return err
+				}
+
+				err := nested(ctx, func(ctx context.Context) error {
+					if err := insert(ctx, 2); err != nil {
+						return err
+					}
+
+					return errRollback
+				})
+				if !errors.Is(err, errRollback) {
+					return err
+				}
+
+				return insert(ctx, 3)
+			},
+			wantIDs: []int{1, 3},
+		},
+		{
+			name: "it should discard the committed nested transactions when the outermost transaction rolls back",
+			run: func(ctx context.Context) error {
+				if err := insert(ctx, 1); err != nil {
+					return err
+				}
+
+				if err := nested(ctx, func(ctx context.Context) error {
+					return insert(ctx, 2)
+				}); err != nil {
+					return err
+				}
+
+				return errRollback
+			},
+			wantErr: errRollback,
+			wantIDs: []int{},
+		},
+		{
+			name: "it should support several levels of nesting",
+			run: func(ctx context.Context) error {
+				if err := insert(ctx, 1); err != nil {
+					return err
+				}
+
+				return nested(ctx, func(ctx context.Context) error {
+					if err := insert(ctx, 2); err != nil {
+						return err
+					}
+
+					err := nested(ctx, func(ctx context.Context) error {
+						if err := insert(ctx, 3); err != nil {
+							return err
+						}
+
+						return nested(ctx, func(ctx context.Context) error {
+							return insert(ctx, 4)
+						})
+					})
+					if err != nil {
+						return err
+					}
+
+					err = nested(ctx, func(ctx context.Context) error {
+						if err := insert(ctx, 5); err != nil {
+							return err
+						}
+
+						return errRollback
+					})
+					if !errors.Is(err, errRollback) {
+						return err
+					}
+
+					return nil
+				})
+			},
+			wantIDs: []int{1, 2, 3, 4},
+		},
+		{
+			name: "it should support sibling nested transactions",
+			run: func(ctx context.Context) error {
+				err := nested(ctx, func(ctx context.Context) error {
+					if err := insert(ctx, 1); err != nil {
+						return err
+					}
+
+					return errRollback
+				})
+				if !errors.Is(err, errRollback) {
+					return err
+				}
+
+				return nested(ctx, func(ctx context.Context) error {
+					return insert(ctx, 2)
+				})
+			},
+			wantIDs: []int{2},
+		},
+		{
+			name: "it should support named savepoints",
+			run: func(ctx context.Context) error {
+				if err := insert(ctx, 1); err != nil {
+					return err
+				}
+
+				err := transactor.WithinSavepoint(ctx, "strategytest", func(ctx context.Context) error {
+					if err := insert(ctx, 2); err != nil {
+						return err
+					}
+
+					return errRollback
+				})
+				if !errors.Is(err, errRollback) {
+					return err
+				}
+
+				return transactor.WithinSavepoint(ctx, "strategytest", func(ctx context.Context) error {
+					return insert(ctx, 3)
+				})
+			},
+			wantIDs: []int{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := db.ExecContext(ctx, "DELETE FROM "+Table); err != nil {
+				t.Fatalf("failed to clear table %s: %v", Table, err)
+			}
+
+			err := transactor.WithinTransaction(ctx, tt.run)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WithinTransaction() error = %v, want %v", err, tt.wantErr)
+			}
+
+			ids, err := readIDs(ctx, db)
+			if err != nil {
+				t.Fatalf("failed to read table %s: %v", Table, err)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("committed ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func readIDs(ctx context.Context, db *sql.DB) ([]int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id FROM "+Table+" ORDER BY id")
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err() //nolint:wrapcheck
+}
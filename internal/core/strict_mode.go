@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// MisuseKind is a kind of transaction misuse detected by the strict mode.
+type MisuseKind int
+
+const (
+	// MisuseUseAfterReturn is the use of a transaction after its WithinTransaction returned.
+	MisuseUseAfterReturn MisuseKind = iota
+	// MisuseConcurrentUse is the use of a transaction by several goroutines at the same time.
+	MisuseConcurrentUse
+	// MisuseRowsOpenAtCommit is the commit of a transaction while rows or batch results it returned are still open.
+	MisuseRowsOpenAtCommit
+	// MisuseNestedFailureIgnored is the commit of a transaction although one of its nested transactions failed
+	// to create, release, or rollback to its savepoint, leaving the transaction in an unknown state.
+	MisuseNestedFailureIgnored
+)
+
+func (k MisuseKind) String() string {
+	switch k {
+	case MisuseUseAfterReturn:
+		return "transaction used after WithinTransaction returned"
+	case MisuseConcurrentUse:
+		return "transaction used by several goroutines concurrently"
+	case MisuseRowsOpenAtCommit:
+		return "rows left open at commit"
+	case MisuseNestedFailureIgnored:
+		return "nested transaction failure ignored"
+	default:
+		return "unknown misuse"
+	}
+}
+
+// MisuseError describes a transaction misuse detected by the strict mode.
+type MisuseError struct {
+	Kind             MisuseKind
+	TransactionStack []byte // The stack trace of the goroutine when it started the transaction.
+	Stack            []byte // The stack trace of the goroutine when the misuse was detected.
+}
+
+func (e *MisuseError) Error() string {
+	return fmt.Sprintf("transaction misuse: %s\n\ntransaction started at:\n%s\ndetected at:\n%s", e.Kind, e.TransactionStack, e.Stack)
+}
+
+// StrictMode configures the strict mode.
+type StrictMode struct {
+	// OnMisuse is called when a misuse is detected, for example to log the diagnostic.
+	// Defaults to panicking with the [*MisuseError].
+	OnMisuse func(*MisuseError)
+}
+
+// SetStrictMode enables the detection of the misuses of the transactions at runtime.
+func (t *Transactor[DB, Tx]) SetStrictMode(config StrictMode) {
+	t.strict = &strictMode{StrictMode: config}
+}
+
+// StrictTx returns the watcher of the transaction of the context, nil if the strict mode is disabled.
+// The DB handlers of the transaction must be wrapped to report their use to it.
+func (t *Transactor[DB, Tx]) StrictTx(ctx context.Context) *StrictTx {
+	if t.strict == nil {
+		return nil
+	}
+
+	return strictTxFromContext(ctx)
+}
+
+type strictMode struct {
+	StrictMode
+}
+
+type strictTxKey struct{}
+
+// StrictTx watches the use of a transaction.
+type StrictTx struct {
+	mode   *strictMode
+	parent *StrictTx
+	stack  []byte
+
+	running      *atomic.Int32 // Statements running on the connection, shared with the nested transactions
+	done         atomic.Bool
+	nestedFailed atomic.Bool
+
+	mu   sync.Mutex
+	rows []func() bool // Report whether the rows returned by the transaction are still open
+}
+
+// start starts watching a transaction, and returns the context to use for it.
+// A nil strict mode doesn't watch anything.
+func (m *strictMode) start(ctx context.Context, outermost bool) (context.Context, *StrictTx) {
+	if m == nil {
+		return ctx, nil
+	}
+
+	tx := &StrictTx{
+		mode:  m,
+		stack: debug.Stack(),
+	}
+
+	if parent := strictTxFromContext(ctx); parent != nil && !outermost {
+		if parent.done.Load() {
+			parent.report(MisuseUseAfterReturn)
+		}
+
+		tx.parent = parent
+		tx.running = parent.running
+	} else {
+		tx.running = &atomic.Int32{}
+	}
+
+	return context.WithValue(ctx, strictTxKey{}, tx), tx
+}
+
+func strictTxFromContext(ctx context.Context) *StrictTx {
+	if tx, ok := ctx.Value(strictTxKey{}).(*StrictTx); ok {
+		return tx
+	}
+
+	return nil
+}
+
+// end marks the transaction as returned.
+func (tx *StrictTx) end() {
+	if tx == nil {
+		return
+	}
+
+	tx.done.Store(true)
+}
+
+// fail records that the savepoint of a nested transaction failed.
+func (tx *StrictTx) fail() {
+	if tx == nil || tx.parent == nil {
+		return
+	}
+
+	tx.parent.nestedFailed.Store(true)
+}
+
+// checkCommit checks that the transaction can be committed.
+func (tx *StrictTx) checkCommit() {
+	if tx == nil {
+		return
+	}
+
+	if tx.nestedFailed.Load() {
+		tx.report(MisuseNestedFailureIgnored)
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	for _, isOpen := range tx.rows {
+		if isOpen() {
+			tx.report(MisuseRowsOpenAtCommit)
+			return
+		}
+	}
+}
+
+// Enter records the start of a statement. The returned function must be called once it's done.
+func (tx *StrictTx) Enter() func() {
+	if tx.done.Load() {
+		tx.report(MisuseUseAfterReturn)
+	}
+
+	if tx.running.Add(1) > 1 {
+		tx.report(MisuseConcurrentUse)
+	}
+
+	return func() {
+		tx.running.Add(-1)
+	}
+}
+
+// Track records rows returned by the transaction, to check whether they're closed at commit.
+func (tx *StrictTx) Track(isOpen func() bool) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	// Forget the rows already closed, so that they don't pile up in long transactions
+	open := tx.rows[:0]
+	for _, isOpen := range tx.rows {
+		if isOpen() {
+			open = append(open, isOpen)
+		}
+	}
+	tx.rows = append(open, isOpen)
+}
+
+func (tx *StrictTx) report(kind MisuseKind) {
+	err := &MisuseError{
+		Kind:             kind,
+		TransactionStack: tx.stack,
+		Stack:            debug.Stack(),
+	}
+
+	if tx.mode.OnMisuse == nil {
+		panic(err)
+	}
+	tx.mode.OnMisuse(err)
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrSubtransactionBudgetExceeded is returned when creating a savepoint past the subtransaction budget,
+// with [SubtransactionsFail].
+var ErrSubtransactionBudgetExceeded = errors.New("subtransaction budget exceeded")
+
+// defaultMaxSubtransactions is the number of subtransactions cached by a PostgreSQL backend.
+const defaultMaxSubtransactions = 64
+
+// SubtransactionOverflow is what happens to the savepoints created past the subtransaction budget.
+type SubtransactionOverflow int
+
+const (
+	// SubtransactionsWarn creates the savepoints anyway, after calling [SubtransactionBudget.OnExceeded].
+	SubtransactionsWarn SubtransactionOverflow = iota
+	// SubtransactionsFail fails the nested transactions and the savepoints with [ErrSubtransactionBudgetExceeded].
+	SubtransactionsFail
+	// SubtransactionsFlatten runs the nested transactions and the savepoints in their parent transaction, without a savepoint.
+	// Their changes can't be rolled back separately: if one of them fails, the outermost transaction is rolled back
+	// instead of committed, and fails with [ErrRollbackOnly].
+	SubtransactionsFlatten
+)
+
+// SubtransactionBudget limits the number of savepoints created within an outermost transaction: by its nested
+// transactions, [RunInSavepoint] and the statement savepoints. On PostgreSQL, every savepoint is a subtransaction, and a backend caches 64 of them per transaction: past them,
+// the subtransactions overflow to the pg_subtrans SLRU, which slows down the visibility checks of every backend,
+// especially on the replicas.
+type SubtransactionBudget struct {
+	// Max is the number of savepoints an outermost transaction can create, released or not. Zero defaults to 64, the number of subtransactions cached by PostgreSQL.
+	Max int
+
+	// Overflow is what happens to the savepoints created past Max.
+	Overflow SubtransactionOverflow
+
+	// OnExceeded is called for every savepoint created past Max, for example to log a warning.
+	// savepoints is the number of savepoints of the outermost transaction, including this one even if it fails or is flattened.
+	OnExceeded func(ctx context.Context, savepoints int)
+}
+
+// SetSubtransactionBudget limits the number of savepoints created within an outermost transaction.
+func (t *Transactor[DB, Tx]) SetSubtransactionBudget(config SubtransactionBudget) {
+	if config.Max <= 0 {
+		config.Max = defaultMaxSubtransactions
+	}
+
+	t.subtransactions = &config
+}
+
+type subtransactionsKey struct{}
+
+// errSubtransactionFlattened is returned when beginning a nested transaction past the subtransaction budget, with
+// [SubtransactionsFlatten]: the core runs it in its parent transaction instead.
+var errSubtransactionFlattened = errors.New("nested transaction flattened into its parent transaction")
+
+// subtransactions is the state of the subtransaction budget of an outermost transaction.
+type subtransactions struct {
+	budget       *SubtransactionBudget
+	savepoints   atomic.Int64
+	rollbackOnly atomic.Bool
+}
+
+// start returns a copy of the context tracking the savepoints of a new outermost transaction.
+// A nil budget doesn't track anything.
+func (b *SubtransactionBudget) start(ctx context.Context) context.Context {
+	if b == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, subtransactionsKey{}, &subtransactions{budget: b})
+}
+
+// subtransactionsFromContext returns the state of the subtransaction budget of the transaction of the context,
+// nil if there's no budget.
+func subtransactionsFromContext(ctx context.Context) *subtransactions {
+	state, _ := ctx.Value(subtransactionsKey{}).(*subtransactions)
+	return state
+}
+
+// spend counts a savepoint about to be created.
+// It reports whether it must be skipped, running its statements in the parent transaction instead.
+// A nil state doesn't count anything.
+func (s *subtransactions) spend(ctx context.Context) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+
+	savepoints := int(s.savepoints.Add(1))
+	if savepoints <= s.budget.Max {
+		return false, nil
+	}
+
+	if s.budget.OnExceeded != nil {
+		s.budget.OnExceeded(ctx, savepoints)
+	}
+
+	switch s.budget.Overflow {
+	case SubtransactionsFail:
+		s.savepoints.Add(-1) // No savepoint is created
+		return false, fmt.Errorf("%w: %d savepoints, the maximum is %d", ErrSubtransactionBudgetExceeded, savepoints, s.budget.Max)
+	case SubtransactionsFlatten:
+		s.savepoints.Add(-1) // No savepoint is created
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// refund uncounts a savepoint that couldn't be created.
+func (s *subtransactions) refund() {
+	if s != nil {
+		s.savepoints.Add(-1)
+	}
+}
+
+// runFlattened runs the function in the parent transaction, instead of a savepoint.
+// If it fails, the outermost transaction becomes rollback-only.
+func (s *subtransactions) runFlattened(ctx context.Context, fn func(context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		s.rollbackOnly.Store(true)
+		return err
+	}
+
+	return nil
+}
+
+// restart forgets the savepoints and the failed flattened savepoints of an outermost transaction restarted from its
+// beginning, since the restart discards them.
+func (s *subtransactions) restart() {
+	if s != nil {
+		s.savepoints.Store(0)
+		s.rollbackOnly.Store(false)
+	}
+}
+
+// checkCommit returns [ErrRollbackOnly] if a flattened savepoint of the outermost transaction failed.
+func (s *subtransactions) checkCommit() error {
+	if s != nil && s.rollbackOnly.Load() {
+		return ErrRollbackOnly
+	}
+
+	return nil
+}
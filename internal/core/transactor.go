@@ -0,0 +1,197 @@
+// Package core implements the transactions shared by the stdlib, sqlx and pgx transactors.
+// Each of them is a thin adapter of its driver to the generic [Transactor].
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Completer commits or rolls back a transaction.
+type Completer interface {
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// Driver adapts a database driver to the [Transactor].
+// DB is the type of the DB handlers, and Tx the type of the transactions.
+type Driver[DB, Tx any] struct {
+	// DB returns the DB handler to begin a transaction with: the one of the transaction of the context, or the original one.
+	DB func(ctx context.Context) DB
+
+	// Begin begins a transaction with the DB handler, a nested one if the DB handler is a transaction.
+	Begin func(ctx context.Context, db DB) (Tx, error)
+
+	// Nest returns the DB handler of the transaction, added to the context of the callback, and the transaction to complete.
+	Nest func(db DB, tx Tx, outermost bool) (DB, Completer)
+
+	// ErrTxDone is the error returned when rolling back a transaction that is already committed or rolled back.
+	ErrTxDone error
+}
+
+// Transactor runs the transactions of a driver.
+// Its features are disabled until enabled by their setter, which must be called before its first transaction.
+type Transactor[DB, Tx any] struct {
+	Driver[DB, Tx]
+
+	inFlight        inFlightTransactions
+	admission       *admissionLimiter
+	breaker         *circuitBreaker
+	deadlocks       *DeadlockDetector
+	strict          *strictMode
+	writers         writerLock
+	subtransactions *SubtransactionBudget
+	concurrencySafe bool
+}
+
+// NewTransactor returns a transactor for the driver.
+func NewTransactor[DB, Tx any](driver Driver[DB, Tx]) *Transactor[DB, Tx] {
+	return &Transactor[DB, Tx]{Driver: driver}
+}
+
+// SetConcurrencySafe makes the transactions provide a lock to serialize their statements, see [TxLockFromContext].
+func (t *Transactor[DB, Tx]) SetConcurrencySafe() {
+	t.concurrencySafe = true
+}
+
+// WithinTransaction executes the given function within a transaction, nested if the context is already within one.
+func (t *Transactor[DB, Tx]) WithinTransaction(ctx context.Context, txFunc func(context.Context) error) (err error) {
+	// The error of the database when beginning or committing the transaction, judged by the circuit breaker
+	var dbErr error
+
+	outermost := !IsWithinTransaction[DB](ctx)
+	if outermost {
+		var done func(error)
+		ctx, done, err = t.startOutermostTransaction(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			done(dbErr)
+		}()
+	}
+
+	parentCtx := ctx
+	ctx, strict := t.strict.start(ctx, outermost)
+	defer strict.end()
+
+	currentDB := t.DB(ctx)
+
+	tx, release, err := t.begin(ctx, currentDB, outermost)
+	if errors.Is(err, errSubtransactionFlattened) {
+		return subtransactionsFromContext(parentCtx).runFlattened(parentCtx, txFunc)
+	}
+	if err != nil {
+		strict.fail()
+		dbErr = err
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	if outermost {
+		defer t.deadlocks.hold()()
+	}
+
+	newDB, currentTX := t.Nest(currentDB, tx, outermost)
+	defer func() {
+		// If the outermost rollback fails, there's nothing to do, the transaction will expire by itself
+		if rollbackErr := currentTX.Rollback(ctx); rollbackErr != nil && !errors.Is(rollbackErr, t.ErrTxDone) {
+			strict.fail()
+
+			// A failed nested rollback leaves its changes in the parent transaction, which must know about it,
+			// for example when the transaction is doomed
+			if !outermost {
+				err = errors.Join(err, fmt.Errorf("failed to rollback transaction: %w", rollbackErr))
+			}
+		}
+	}()
+	txCtx := TxToContext(ctx, newDB)
+
+	if err := t.run(ctx, txCtx, currentTX, outermost, txFunc); err != nil {
+		return err
+	}
+
+	strict.checkCommit()
+	if outermost {
+		if err := subtransactionsFromContext(ctx).checkCommit(); err != nil {
+			return err
+		}
+	}
+
+	if err := currentTX.Commit(ctx); err != nil {
+		strict.fail()
+		dbErr = err
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// startOutermostTransaction applies the checks required before starting an outermost transaction.
+// It returns the context to use for the transaction, and a function to call with the error of the database once it's done.
+func (t *Transactor[DB, Tx]) startOutermostTransaction(ctx context.Context) (context.Context, func(error), error) {
+	ctx, done, err := t.inFlight.start(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.concurrencySafe {
+		ctx = txLockToContext(ctx)
+	}
+	ctx = t.subtransactions.start(ctx)
+
+	call, err := t.breaker.allow()
+	if err != nil {
+		done()
+		return nil, nil, err
+	}
+
+	release, err := t.admission.acquire(ctx)
+	if err != nil {
+		call.abandon()
+		done()
+		return nil, nil, err
+	}
+
+	ctx, unlock, err := t.writers.acquire(ctx)
+	if err != nil {
+		release()
+		call.abandon()
+		done()
+		return nil, nil, err
+	}
+
+	return ctx, func(err error) {
+		unlock()
+		call.done(err)
+		release()
+		done()
+	}, nil
+}
+
+// begin begins a transaction, watching for pool deadlocks if it's an outermost transaction.
+// The returned function must be called once the transaction is done.
+func (t *Transactor[DB, Tx]) begin(ctx context.Context, db DB, outermost bool) (Tx, func(), error) {
+	if !outermost {
+		tx, err := t.Begin(ctx, db)
+		return tx, func() {}, err
+	}
+
+	// The watched context is only canceled once the transaction is done, since database/sql rolls back
+	// a transaction as soon as the context it was begun with is canceled
+	watchCtx, stop, cancel := t.deadlocks.watch(ctx)
+	tx, err := t.Begin(watchCtx, db)
+	stop()
+	if err != nil {
+		cancel()
+		return tx, nil, DeadlockCause(watchCtx, err)
+	}
+
+	return tx, cancel, nil
+}
+
+// Shutdown refuses the new outermost transactions and waits for the running ones to finish.
+// If the context expires first, the remaining transactions are aborted by canceling their context,
+// and Shutdown returns how many were aborted along with the context error, without waiting for their rollback.
+func (t *Transactor[DB, Tx]) Shutdown(ctx context.Context) (int, error) {
+	return t.inFlight.drain(ctx)
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrWriterLockHeld is returned when an outermost read-write transaction is begun from a context descending from the
+// one of the running read-write transaction, such as the one returned by DetachedContext within the transaction:
+// waiting for the writer lock would deadlock if the running transaction waits for the new one.
+var ErrWriterLockHeld = errors.New("writer lock already held by the transaction of the context")
+
+// SetWriterLock serializes the outermost read-write transactions: only one of them runs at a time,
+// while the read-only ones, see [WithReadOnly], run concurrently.
+// It's meant for the databases allowing a single writer, such as SQLite.
+func (t *Transactor[DB, Tx]) SetWriterLock() {
+	t.writers = make(writerLock, 1)
+}
+
+// writerLock is a semaphore held by the outermost read-write transaction running.
+type writerLock chan struct{}
+
+type writerLockKey struct{}
+
+// writerLockHolder is recorded in the context of the transaction holding the writer lock.
+type writerLockHolder struct {
+	lock     writerLock
+	released atomic.Bool
+}
+
+// acquire waits for the writer lock if the transaction of the context is read-write.
+// It returns the context of the transaction, recording that it holds the lock, and a function to call once the
+// transaction is done. A nil lock is never held.
+func (l writerLock) acquire(ctx context.Context) (context.Context, func(), error) {
+	if l == nil || IsReadOnly(ctx) {
+		return ctx, func() {}, nil
+	}
+
+	// The contexts derived from the one of the transaction keep its values, even once detached from it
+	if holder, _ := ctx.Value(writerLockKey{}).(*writerLockHolder); holder != nil && holder.lock == l && !holder.released.Load() {
+		return nil, nil, ErrWriterLockHeld
+	}
+
+	select {
+	case l <- struct{}{}:
+		holder := &writerLockHolder{lock: l}
+		return context.WithValue(ctx, writerLockKey{}, holder), func() {
+			holder.released.Store(true)
+			<-l
+		}, nil
+
+	case <-ctx.Done():
+		return nil, nil, fmt.Errorf("failed to wait for the writer lock: %w", context.Cause(ctx))
+	}
+}
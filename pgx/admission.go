@@ -0,0 +1,50 @@
+package pgx
+
+import (
+	"context"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+// ErrAdmissionTimeout is returned when a transaction waited longer than [AdmissionControl.MaxWait] to be admitted.
+var ErrAdmissionTimeout = core.ErrAdmissionTimeout
+
+type (
+	// Priority is the priority class of an outermost transaction.
+	// When the admission control is enabled, waiting transactions are admitted by priority, then in order of arrival.
+	Priority = core.Priority
+
+	// AdmissionControl configures the admission control of the outermost transactions.
+	AdmissionControl = core.AdmissionControl
+
+	// AdmissionStats contains the admission control statistics.
+	AdmissionStats = core.AdmissionStats
+)
+
+const (
+	PriorityLow    = core.PriorityLow
+	PriorityNormal = core.PriorityNormal
+	PriorityHigh   = core.PriorityHigh
+)
+
+// WithPriority returns a context in which the outermost transactions are admitted with the given priority.
+// Transactions are admitted with [PriorityNormal] by default.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return core.WithPriority(ctx, priority)
+}
+
+// WithAdmissionControl limits the number of outermost transactions running concurrently.
+// The transactions exceeding the limit are queued until a running transaction finishes.
+// Nested transactions are not subject to the admission control since they reuse the connection of their parent.
+// It panics if [AdmissionControl.MaxConcurrentTransactions] is not positive.
+func WithAdmissionControl(config AdmissionControl) Option {
+	return func(t *Transactor) {
+		t.core.SetAdmissionControl(config)
+	}
+}
+
+// AdmissionStats returns the admission control statistics.
+// It returns zero stats if the admission control is disabled.
+func (t *Transactor) AdmissionStats() AdmissionStats {
+	return t.core.AdmissionStats()
+}
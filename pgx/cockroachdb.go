@@ -0,0 +1,53 @@
+package pgx
+
+import (
+	"context"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jackc/pgx/v5"
+)
+
+// WithCockroachDB makes the outermost transactions follow the client-side retry protocol of CockroachDB: they begin
+// with SAVEPOINT cockroach_restart, and when they fail with a retryable error (SQLSTATE 40001), they're rolled back to
+// it and the callback of WithinTransaction runs again, until RELEASE SAVEPOINT cockroach_restart succeeds.
+// The callback must therefore be safe to run several times. The nested transactions use savepoints.
+func WithCockroachDB() Option {
+	return func(t *Transactor) {
+		t.cockroachDB = true
+	}
+}
+
+// nest returns the transaction begun by the core, naming the savepoints of its nested transactions with the prefix,
+// restarted with the retry protocol of CockroachDB if enabled, and started with the session settings if any.
+func (t *Transactor) nest(_ pgxDB, tx pgx.Tx, outermost bool) (pgxDB, core.Completer) {
+	if !outermost {
+		return tx, tx
+	}
+
+	newDB := newNamedSavepointsTx(tx, t.savepointPrefix)
+
+	var completer core.Completer = tx
+	if t.cockroachDB {
+		completer = restartableTx{Tx: tx, Restarter: core.CockroachRestart[pgx.Tx]{Tx: tx, Exec: execStatement}}
+	}
+
+	// The session settings are applied once the transaction is started, after the savepoint of the retry protocol
+	if t.sessionSettings != nil {
+		completer = core.StartWith(completer, func(ctx context.Context) error {
+			return t.applySessionSettings(ctx, tx)
+		})
+	}
+
+	return newDB, completer
+}
+
+// restartableTx is an outermost transaction restarted by the core when it fails with a retryable error.
+type restartableTx struct {
+	pgx.Tx
+	core.Restarter
+}
+
+func execStatement(ctx context.Context, tx pgx.Tx, query string) error {
+	_, err := tx.Exec(ctx, query)
+	return err //nolint:wrapcheck
+}
@@ -0,0 +1,125 @@
+package pgx
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// WithConcurrencySafeTransactions makes the DB handler returned by the [DBGetter] within a transaction safe for concurrent use,
+// for example by goroutines of an errgroup started in the callback of WithinTransaction.
+// The statements are serialized on the transaction: the [pgx.Rows], [pgx.Row] and [pgx.BatchResults] hold the transaction
+// until they're closed, scanned or read entirely, so they must not be left open.
+// Nested transactions must not be started concurrently.
+func WithConcurrencySafeTransactions() Option {
+	return func(t *Transactor) {
+		t.concurrencySafe = true
+		t.core.SetConcurrencySafe()
+	}
+}
+
+// concurrencySafeDB returns a concurrency safe DB handler for the transaction, if enabled.
+func (t *Transactor) concurrencySafeDB(ctx context.Context, tx DB) DB {
+	if !t.concurrencySafe {
+		return tx
+	}
+
+	lock := core.TxLockFromContext(ctx)
+	if lock == nil {
+		return tx
+	}
+
+	return &concurrencySafeTx{tx: tx, lock: lock}
+}
+
+// concurrencySafeTx is a DB handler serializing the statements executed on a shared transaction.
+type concurrencySafeTx struct {
+	tx   DB
+	lock *sync.Mutex
+}
+
+func (db *concurrencySafeTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.tx.Exec(ctx, sql, arguments...) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	db.lock.Lock()
+
+	rows, err := db.tx.Query(ctx, sql, args...)
+	if err != nil {
+		db.lock.Unlock()
+		return nil, err //nolint:wrapcheck
+	}
+
+	return &lockedRows{Rows: rows, unlock: sync.OnceFunc(db.lock.Unlock)}, nil
+}
+
+func (db *concurrencySafeTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	db.lock.Lock()
+
+	return &lockedRow{Row: db.tx.QueryRow(ctx, sql, args...), unlock: sync.OnceFunc(db.lock.Unlock)}
+}
+
+func (db *concurrencySafeTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.tx.CopyFrom(ctx, tableName, columnNames, rowSrc) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	db.lock.Lock()
+
+	return &lockedBatchResults{BatchResults: db.tx.SendBatch(ctx, b), unlock: sync.OnceFunc(db.lock.Unlock)}
+}
+
+// lockedRows releases the transaction once the rows are closed or read entirely.
+type lockedRows struct {
+	pgx.Rows
+	unlock func()
+}
+
+func (r *lockedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	// The rows are closed automatically once read entirely
+	r.unlock()
+	return false
+}
+
+func (r *lockedRows) Close() {
+	r.Rows.Close()
+	r.unlock()
+}
+
+// lockedRow releases the transaction once the row is scanned.
+type lockedRow struct {
+	pgx.Row
+	unlock func()
+}
+
+func (r *lockedRow) Scan(dest ...any) error {
+	defer r.unlock()
+
+	return r.Row.Scan(dest...) //nolint:wrapcheck
+}
+
+// lockedBatchResults releases the transaction once the batch results are closed.
+type lockedBatchResults struct {
+	pgx.BatchResults
+	unlock func()
+}
+
+func (r *lockedBatchResults) Close() error {
+	defer r.unlock()
+
+	return r.BatchResults.Close() //nolint:wrapcheck
+}
@@ -0,0 +1,159 @@
+package pgx
+
+import (
+	"context"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type (
+	// DeadlockError describes a goroutine waiting for a connection of an exhausted pool while it's holding a transaction,
+	// which can only be released by the goroutine itself.
+	DeadlockError = core.DeadlockError
+
+	// DeadlockDetection configures the pool deadlock detection.
+	DeadlockDetection = core.DeadlockDetection
+)
+
+// WithDeadlockDetection enables a debug mode detecting the goroutines that wait for a connection of an exhausted pool
+// while holding a transaction, for example by using the DB handler returned by the [DBGetter] with a context that is not
+// the one of their transaction, or by starting a new outermost transaction from within a transaction.
+// A [pgx.Conn] can't be exhausted since using it concurrently fails immediately, so it's never watched.
+// The calls are only watched until they acquire their connection: a query running for longer than the threshold is not reported.
+// It relies on stack traces to identify the goroutines, so it should only be used in development.
+// It panics if [DeadlockDetection.Threshold] is not positive.
+func WithDeadlockDetection(config DeadlockDetection) Option {
+	return func(t *Transactor) {
+		t.core.SetDeadlockDetection(config)
+	}
+}
+
+// deadlockWatchingDB returns a DB handler watching for deadlocks.
+// It returns the DB handler as is if the deadlock detection is disabled.
+func (t *Transactor) deadlockWatchingDB(db DB) DB {
+	detector := t.core.DeadlockDetector()
+	pool, ok := db.(*pgxpool.Pool)
+	if detector == nil || !ok {
+		return db
+	}
+
+	return &deadlockWatchingDB{Pool: pool, detector: detector}
+}
+
+// deadlockWatchingDB runs the statements on a dedicated connection, so that the watch stops as soon as it's acquired.
+type deadlockWatchingDB struct {
+	*pgxpool.Pool
+	detector *core.DeadlockDetector
+}
+
+// acquire acquires a connection, watching for deadlocks until it's acquired.
+func (db *deadlockWatchingDB) acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	watchCtx, stop := db.detector.Watch(ctx)
+	conn, err := db.Pool.Acquire(watchCtx)
+	stop()
+
+	return conn, core.DeadlockCause(watchCtx, err)
+}
+
+func (db *deadlockWatchingDB) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer conn.Release()
+
+	return conn.Exec(ctx, sql, arguments...) //nolint:wrapcheck
+}
+
+func (db *deadlockWatchingDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		conn.Release()
+		return nil, err //nolint:wrapcheck
+	}
+
+	return &releasingRows{Rows: rows, conn: conn}, nil
+}
+
+func (db *deadlockWatchingDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+
+	return &releasingRow{row: conn.QueryRow(ctx, sql, args...), conn: conn}
+}
+
+func (db *deadlockWatchingDB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	return conn.CopyFrom(ctx, tableName, columnNames, rowSrc) //nolint:wrapcheck
+}
+
+func (db *deadlockWatchingDB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return errBatchResults{err: err}
+	}
+
+	return &releasingBatchResults{BatchResults: conn.SendBatch(ctx, b), conn: conn}
+}
+
+// releasingRows releases the connection of the rows once they're closed, like the rows of a [pgxpool.Pool].
+type releasingRows struct {
+	pgx.Rows
+	conn *pgxpool.Conn
+}
+
+func (r *releasingRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	r.Close()
+	return false
+}
+
+func (r *releasingRows) Close() {
+	r.Rows.Close()
+	if r.conn != nil {
+		r.conn.Release()
+		r.conn = nil
+	}
+}
+
+// releasingRow releases the connection of the row once it's scanned, like the row of a [pgxpool.Pool].
+type releasingRow struct {
+	row  pgx.Row
+	conn *pgxpool.Conn
+}
+
+func (r *releasingRow) Scan(dest ...any) error {
+	defer r.conn.Release()
+
+	return r.row.Scan(dest...) //nolint:wrapcheck
+}
+
+// releasingBatchResults releases the connection of the batch results once they're closed, like the batch results of a [pgxpool.Pool].
+type releasingBatchResults struct {
+	pgx.BatchResults
+	conn *pgxpool.Conn
+}
+
+func (br *releasingBatchResults) Close() error {
+	defer br.conn.Release()
+
+	return br.BatchResults.Close() //nolint:wrapcheck
+}
@@ -30,5 +30,12 @@ func NewFakeTransactorFromPool(db *pgxpool.Pool) (FakeTransactor, DBGetter) {
 type FakeTransactor struct{}
 
 func (FakeTransactor) WithinTransaction(ctx context.Context, txFunc func(context.Context) error) error {
-	return txFunc(ctx)
+	return txFunc(context.WithValue(ctx, fakeTransactionKey{}, true))
+}
+
+// fakeTransactionKey marks the contexts within a fake transaction, so that the [TxGetter] accepts them.
+type fakeTransactionKey struct{}
+
+func isWithinFakeTransaction(ctx context.Context) bool {
+	return ctx.Value(fakeTransactionKey{}) != nil
 }
@@ -2,7 +2,10 @@ module github.com/Thiht/transactor/pgx
 
 go 1.25.0
 
-require github.com/jackc/pgx/v5 v5.9.1
+require (
+	github.com/Thiht/transactor v0.0.0
+	github.com/jackc/pgx/v5 v5.9.1
+)
 
 require (
 	github.com/jackc/pgpassfile v1.0.0 // indirect
@@ -11,3 +14,5 @@ require (
 	golang.org/x/sync v0.20.0 // indirect
 	golang.org/x/text v0.35.0 // indirect
 )
+
+replace github.com/Thiht/transactor => ../
@@ -0,0 +1,91 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jackc/pgx/v5"
+)
+
+type (
+	// ProbeReport is the report of the capabilities of a database returned by [Probe].
+	ProbeReport = core.ProbeReport
+
+	// ProbeCheck is the outcome of a capability check.
+	ProbeCheck = core.ProbeCheck
+)
+
+// txBeginner is implemented by [pgx.Conn] and [pgxpool.Pool].
+type txBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// Probe checks that the nested transactions work with the database, for example at startup or in CI.
+// It checks the creation, release and rollback of a savepoint, and the optional checks of the read-only transactions
+// and of the isolation levels accepted by the database. Every check runs in a transaction that is rolled back.
+// It only returns an error if a transaction can't be started at all, for example if the database is unreachable.
+func Probe(ctx context.Context, db txBeginner) (ProbeReport, error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return ProbeReport{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	_ = tx.Rollback(ctx)
+
+	var report ProbeReport
+	report.Checks = append(report.Checks, probeSavepoints(ctx, db)...)
+
+	report.Checks = append(report.Checks, ProbeCheck{
+		Name:     "read only",
+		Err:      probeTxOptions(ctx, db, pgx.TxOptions{AccessMode: pgx.ReadOnly}),
+		Optional: true,
+	})
+
+	for _, level := range []pgx.TxIsoLevel{
+		pgx.ReadUncommitted,
+		pgx.ReadCommitted,
+		pgx.RepeatableRead,
+		pgx.Serializable,
+	} {
+		report.Checks = append(report.Checks, ProbeCheck{
+			Name:     "isolation level " + string(level),
+			Err:      probeTxOptions(ctx, db, pgx.TxOptions{IsoLevel: level}),
+			Optional: true,
+		})
+	}
+
+	return report, nil
+}
+
+// probeSavepoints checks the creation, release and rollback of a savepoint with the nested transactions of pgx.
+func probeSavepoints(ctx context.Context, db txBeginner) []ProbeCheck {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		err = fmt.Errorf("failed to begin transaction: %w", err)
+		return core.ProbeSavepoints(ctx, func(context.Context) (core.Completer, error) {
+			return nil, err
+		})
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	return core.ProbeSavepoints(ctx, func(ctx context.Context) (core.Completer, error) {
+		return tx.Begin(ctx) //nolint:wrapcheck
+	})
+}
+
+// probeTxOptions checks that a transaction can be started with the given options.
+func probeTxOptions(ctx context.Context, db txBeginner, opts pgx.TxOptions) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		return err //nolint:wrapcheck
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package pgx
+
+import (
+	"context"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultSavepointPrefix is the prefix of the savepoint names of the nested transactions.
+const DefaultSavepointPrefix = core.DefaultSavepointPrefix
+
+// WithSavepointPrefix sets the prefix of the savepoint names of the nested transactions, [DefaultSavepointPrefix] by default.
+// The savepoints are named <prefix><id>_<depth> instead of the sp_<depth> names of pgx, where id is unique to each outermost
+// transaction, so that they don't collide with the savepoints created by your own SQL, and can be told apart in the logs.
+// The prefix must start with a letter and only contain letters, digits and underscores, NewTransactor panics with
+// [ErrInvalidSavepointName] otherwise. The nested transactions fail with [ErrInvalidSavepointName] if their savepoint name
+// exceeds the 63 characters of the PostgreSQL identifiers.
+func WithSavepointPrefix(prefix string) Option {
+	return func(t *Transactor) {
+		t.savepointPrefix = prefix
+	}
+}
+
+// namedSavepointsTx is a transaction whose nested transactions are savepoints named with the savepoint prefix.
+type namedSavepointsTx struct {
+	pgx.Tx
+	nested *core.NestedTransaction[pgx.Tx]
+}
+
+// newNamedSavepointsTx returns the outermost transaction, naming the savepoints of its nested transactions with the prefix.
+func newNamedSavepointsTx(tx pgx.Tx, prefix string) *namedSavepointsTx {
+	strategy := core.SavepointsStrategy[pgx.Tx]{Dialect: core.SavepointsDialect(), Exec: execStatement}
+	nested := core.NewNestedTransaction[pgx.Tx](strategy, tx, pgx.ErrTxClosed)
+	nested.SetSavepointNames(core.NewSavepointNames(prefix))
+
+	return &namedSavepointsTx{Tx: tx, nested: nested}
+}
+
+func (tx *namedSavepointsTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	if err := tx.nested.Begin(ctx); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return &nestedSavepointTx{namedSavepointsTx{Tx: tx.Tx, nested: tx.nested.Nest()}}, nil
+}
+
+// nestedSavepointTx is a nested transaction begun by a [namedSavepointsTx].
+type nestedSavepointTx struct {
+	namedSavepointsTx
+}
+
+func (tx *nestedSavepointTx) Commit(ctx context.Context) error {
+	return tx.nested.Commit(ctx) //nolint:wrapcheck
+}
+
+func (tx *nestedSavepointTx) Rollback(ctx context.Context) error {
+	return tx.nested.Rollback(ctx) //nolint:wrapcheck
+}
@@ -0,0 +1,88 @@
+package pgx
+
+import (
+	"context"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+// ErrInvalidSavepointName is returned when a savepoint name is not a valid SQL identifier.
+var ErrInvalidSavepointName = core.ErrInvalidSavepointName
+
+// SavepointError is returned when a savepoint can't be created, released, or rolled back to.
+// The transaction should then be considered unusable.
+type SavepointError = core.SavepointError
+
+// WithinSavepoint executes the given function within a named savepoint of the current transaction.
+// If the function fails, its changes are rolled back to the savepoint and its error is returned as is, so that it can be
+// inspected and swallowed while the transaction stays usable. If the savepoint itself fails, a [*SavepointError] is returned.
+// The name must not exceed the 63 characters of the PostgreSQL identifiers, or it fails with [ErrInvalidSavepointName]
+// before creating the savepoint.
+// Unlike a nested WithinTransaction, the function runs in the current transaction, without a new transaction scope.
+func (t *Transactor) WithinSavepoint(ctx context.Context, name string, fn func(context.Context) error) error {
+	fnErr, err := t.withinSavepoint(ctx, name, fn)
+	if err != nil {
+		return err
+	}
+
+	return fnErr
+}
+
+// TrySavepoint executes the given function within a named savepoint of the current transaction, like [Transactor.WithinSavepoint].
+// If the function fails with an error for which recoverable returns true, its changes are rolled back to the savepoint
+// and TrySavepoint returns false without error, so that an alternative can be tried in the same transaction.
+func (t *Transactor) TrySavepoint(ctx context.Context, name string, fn func(context.Context) error, recoverable func(error) bool) (bool, error) {
+	fnErr, err := t.withinSavepoint(ctx, name, fn)
+	switch {
+	case err != nil:
+		return false, err
+	case fnErr == nil:
+		return true, nil
+	case recoverable(fnErr):
+		return false, nil
+	default:
+		return false, fnErr
+	}
+}
+
+// withinSavepoint returns the error of the function separately from the error of the savepoint.
+func (t *Transactor) withinSavepoint(ctx context.Context, name string, fn func(context.Context) error) (error, error) {
+	if !IsWithinTransaction(ctx) {
+		return nil, ErrNoTransaction
+	}
+
+	if err := core.ValidateSavepointName(name); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return runInSavepoint(ctx, txFromContext(ctx), name, fn)
+}
+
+// runInSavepoint executes the function within a savepoint, and returns its error separately from the error of the savepoint.
+func runInSavepoint(ctx context.Context, tx DB, name string, fn func(context.Context) error) (error, error) {
+	return core.RunInSavepoint(ctx, savepointer{tx}, name, fn) //nolint:wrapcheck
+}
+
+// savepointer creates, releases, and rolls back to the savepoints of a transaction for the core.
+type savepointer struct {
+	tx DB
+}
+
+func (sp savepointer) Savepoint(ctx context.Context, name string) error {
+	_, err := sp.tx.Exec(ctx, "SAVEPOINT "+name)
+	return err //nolint:wrapcheck
+}
+
+func (sp savepointer) ReleaseSavepoint(ctx context.Context, name string) error {
+	_, err := sp.tx.Exec(ctx, "RELEASE SAVEPOINT "+name)
+	return err //nolint:wrapcheck
+}
+
+func (sp savepointer) RollbackToSavepoint(ctx context.Context, name string) error {
+	_, err := sp.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err //nolint:wrapcheck
+}
+
+func (sp savepointer) MaxSavepointNameLength() int {
+	return core.SavepointsDialect().MaxNameLength
+}
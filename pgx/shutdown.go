@@ -0,0 +1,9 @@
+package pgx
+
+import (
+	"github.com/Thiht/transactor/internal/core"
+)
+
+// ErrShuttingDown is returned when starting a new transaction on a transactor that is shutting down.
+// It's also the cause of the context cancellation of the transactions aborted by [Transactor.Shutdown].
+var ErrShuttingDown = core.ErrShuttingDown
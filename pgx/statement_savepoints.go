@@ -0,0 +1,228 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// WithStatementSavepoints wraps each statement executed by the DB handler returned by the [DBGetter] within a transaction
+// in an implicit savepoint, like the ON_ERROR_ROLLBACK setting of psql: a failed statement is rolled back to its savepoint,
+// so that the transaction stays usable and the error can be handled by the caller, for example a unique violation.
+//
+// It comes at a cost: every statement takes two additional round trips to create and release its savepoint,
+// and every savepoint is a subtransaction. The savepoint of a query is released once its [pgx.Rows], [pgx.Row]
+// or [pgx.BatchResults] are closed, scanned or read entirely, so they must not be left open.
+func WithStatementSavepoints() Option {
+	return func(t *Transactor) {
+		t.statementSavepoints = true
+	}
+}
+
+// statementSavepointsDB returns a DB handler wrapping the statements of the transaction in savepoints, if enabled.
+func (t *Transactor) statementSavepointsDB(tx DB) DB {
+	if !t.statementSavepoints {
+		return tx
+	}
+
+	return &statementSavepointsTx{tx: tx, name: core.NewSavepointNames(t.savepointPrefix).Statement()}
+}
+
+// statementSavepointsTx is a DB handler wrapping each statement in a savepoint.
+type statementSavepointsTx struct {
+	tx   DB
+	name string // The name of the savepoints, statements run one at a time on a transaction so a single name is enough
+}
+
+func (db *statementSavepointsTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := db.statement(ctx, func(ctx context.Context) error {
+		var err error
+		tag, err = db.tx.Exec(ctx, sql, arguments...)
+		return err //nolint:wrapcheck
+	})
+
+	return tag, err
+}
+
+func (db *statementSavepointsTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := db.savepoint(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.release(ctx, err)
+	}
+
+	return &savepointRows{Rows: rows, release: db.releaseOnce(ctx)}, nil
+}
+
+func (db *statementSavepointsTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := db.savepoint(ctx); err != nil {
+		return errRow{err: err}
+	}
+
+	return &savepointRow{Row: db.tx.QueryRow(ctx, sql, args...), release: db.releaseOnce(ctx)}
+}
+
+func (db *statementSavepointsTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	var copied int64
+	err := db.statement(ctx, func(ctx context.Context) error {
+		var err error
+		copied, err = db.tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+		return err //nolint:wrapcheck
+	})
+
+	return copied, err
+}
+
+func (db *statementSavepointsTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if err := db.savepoint(ctx); err != nil {
+		return errBatchResults{err: err}
+	}
+
+	return &savepointBatchResults{BatchResults: db.tx.SendBatch(ctx, b), release: db.releaseOnce(ctx)}
+}
+
+// statement executes a statement within a savepoint, rolled back to if the statement fails.
+func (db *statementSavepointsTx) statement(ctx context.Context, exec func(context.Context) error) error {
+	execErr, err := runInSavepoint(ctx, db.tx, db.name, exec)
+	if err != nil {
+		return err
+	}
+
+	return execErr
+}
+
+func (db *statementSavepointsTx) savepoint(ctx context.Context) error {
+	if _, err := db.tx.Exec(ctx, "SAVEPOINT "+db.name); err != nil {
+		return &SavepointError{Name: db.name, Op: "create", Err: err}
+	}
+
+	return nil
+}
+
+// release releases the savepoint of a statement, or rolls back to it if the statement failed.
+// It returns the error of the statement, or a [*SavepointError] if the savepoint failed.
+func (db *statementSavepointsTx) release(ctx context.Context, execErr error) error {
+	if execErr != nil {
+		if _, err := db.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+db.name); err != nil {
+			return &SavepointError{Name: db.name, Op: "rollback to", Err: err, Cause: execErr}
+		}
+
+		return execErr
+	}
+
+	if _, err := db.tx.Exec(ctx, "RELEASE SAVEPOINT "+db.name); err != nil {
+		return &SavepointError{Name: db.name, Op: "release", Err: err}
+	}
+
+	return nil
+}
+
+// releaseOnce returns a function releasing the savepoint of a statement whose results are read later.
+// Only its first call releases the savepoint, the next ones return the same error.
+func (db *statementSavepointsTx) releaseOnce(ctx context.Context) func(error) error {
+	var (
+		once sync.Once
+		err  error
+	)
+
+	return func(execErr error) error {
+		once.Do(func() {
+			err = db.release(ctx, execErr)
+		})
+
+		return err
+	}
+}
+
+// savepointRows releases the savepoint of the query once the rows are closed or read entirely.
+type savepointRows struct {
+	pgx.Rows
+	release func(error) error
+	err     error
+	done    bool
+}
+
+func (r *savepointRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	// The rows are closed automatically once read entirely
+	r.finish()
+	return false
+}
+
+func (r *savepointRows) Close() {
+	r.Rows.Close()
+	r.finish()
+}
+
+func (r *savepointRows) Err() error {
+	if r.done {
+		return r.err
+	}
+
+	return r.Rows.Err() //nolint:wrapcheck
+}
+
+func (r *savepointRows) finish() {
+	r.err = r.release(r.Rows.Err())
+	r.done = true
+}
+
+// savepointRow releases the savepoint of the query once the row is scanned.
+type savepointRow struct {
+	pgx.Row
+	release func(error) error
+}
+
+func (r *savepointRow) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// No rows is not a failure of the statement
+		if releaseErr := r.release(nil); releaseErr != nil {
+			return releaseErr
+		}
+
+		return err //nolint:wrapcheck
+	}
+
+	return r.release(err)
+}
+
+// savepointBatchResults releases the savepoint of the batch once the batch results are closed.
+type savepointBatchResults struct {
+	pgx.BatchResults
+	release func(error) error
+}
+
+func (r *savepointBatchResults) Close() error {
+	return r.release(r.BatchResults.Close())
+}
+
+// errRow is a row failing with an error, such as the one of its savepoint.
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
+
+// errBatchResults are batch results failing with an error, such as the one of their savepoint.
+type errBatchResults struct {
+	err error
+}
+
+func (r errBatchResults) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, r.err }
+func (r errBatchResults) Query() (pgx.Rows, error)         { return nil, r.err }
+func (r errBatchResults) QueryRow() pgx.Row                { return errRow(r) }
+func (r errBatchResults) Close() error                     { return r.err }
@@ -0,0 +1,154 @@
+package pgx
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type (
+	// MisuseKind is a kind of transaction misuse detected by the strict mode.
+	MisuseKind = core.MisuseKind
+
+	// MisuseError describes a transaction misuse detected by the strict mode.
+	MisuseError = core.MisuseError
+
+	// StrictMode configures the strict mode.
+	StrictMode = core.StrictMode
+)
+
+const (
+	// MisuseUseAfterReturn is the use of a transaction after its WithinTransaction returned.
+	MisuseUseAfterReturn = core.MisuseUseAfterReturn
+	// MisuseConcurrentUse is the use of a transaction by several goroutines at the same time.
+	MisuseConcurrentUse = core.MisuseConcurrentUse
+	// MisuseRowsOpenAtCommit is the commit of a transaction while rows or batch results it returned are still open.
+	MisuseRowsOpenAtCommit = core.MisuseRowsOpenAtCommit
+	// MisuseNestedFailureIgnored is the commit of a transaction although one of its nested transactions failed
+	// to create, release, or rollback to its savepoint, leaving the transaction in an unknown state.
+	MisuseNestedFailureIgnored = core.MisuseNestedFailureIgnored
+)
+
+// WithStrictMode enables a debug mode detecting the misuses of the transactions at runtime:
+// using a transaction after its WithinTransaction returned, using it from several goroutines concurrently,
+// leaving rows open at commit, and committing although a nested transaction failed on its savepoint.
+// The DB handlers returned by the [DBGetter] within a transaction are wrapped to watch their use.
+// It relies on stack traces, so it should only be used in development.
+func WithStrictMode(config StrictMode) Option {
+	return func(t *Transactor) {
+		t.core.SetStrictMode(config)
+	}
+}
+
+// strictDB returns a DB handler watching the use of the transaction of the context.
+// It returns the DB handler as is if the strict mode is disabled.
+func (t *Transactor) strictDB(ctx context.Context, db DB) DB {
+	tx := t.core.StrictTx(ctx)
+	if tx == nil {
+		return db
+	}
+
+	return &strictDB{DB: db, tx: tx}
+}
+
+// track records rows or batch results returned by the transaction, to check whether they're closed at commit.
+// The returned function must be called once they're closed.
+func track(tx *core.StrictTx) func() {
+	var closed atomic.Bool
+	tx.Track(func() bool {
+		return !closed.Load()
+	})
+
+	return sync.OnceFunc(func() {
+		closed.Store(true)
+	})
+}
+
+type strictDB struct {
+	DB
+	tx *core.StrictTx
+}
+
+func (db *strictDB) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	defer db.tx.Enter()()
+
+	return db.DB.Exec(ctx, sql, arguments...) //nolint:wrapcheck
+}
+
+func (db *strictDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	defer db.tx.Enter()()
+
+	rows, err := db.DB.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return &strictRows{Rows: rows, close: track(db.tx)}, nil
+}
+
+func (db *strictDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	defer db.tx.Enter()()
+
+	return &strictRow{Row: db.DB.QueryRow(ctx, sql, args...), close: track(db.tx)}
+}
+
+func (db *strictDB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	defer db.tx.Enter()()
+
+	return db.DB.CopyFrom(ctx, tableName, columnNames, rowSrc) //nolint:wrapcheck
+}
+
+func (db *strictDB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	defer db.tx.Enter()()
+
+	return &strictBatchResults{BatchResults: db.DB.SendBatch(ctx, b), close: track(db.tx)}
+}
+
+// strictRows records when the rows are closed or read entirely.
+type strictRows struct {
+	pgx.Rows
+	close func()
+}
+
+func (r *strictRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	// The rows are closed automatically once read entirely
+	r.close()
+	return false
+}
+
+func (r *strictRows) Close() {
+	r.Rows.Close()
+	r.close()
+}
+
+// strictRow records when the row is scanned.
+type strictRow struct {
+	pgx.Row
+	close func()
+}
+
+func (r *strictRow) Scan(dest ...any) error {
+	defer r.close()
+
+	return r.Row.Scan(dest...) //nolint:wrapcheck
+}
+
+// strictBatchResults records when the batch results are closed.
+type strictBatchResults struct {
+	pgx.BatchResults
+	close func()
+}
+
+func (r *strictBatchResults) Close() error {
+	defer r.close()
+
+	return r.BatchResults.Close() //nolint:wrapcheck
+}
@@ -2,88 +2,123 @@ package pgx
 
 import (
 	"context"
-	"fmt"
 
+	"github.com/Thiht/transactor/internal/core"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func NewTransactor(db *pgx.Conn) (*Transactor, DBGetter) {
-	pgxTxGetter := func(ctx context.Context) pgxDB {
-		if tx := txFromContext(ctx); tx != nil {
-			return tx
-		}
+func NewTransactor(db *pgx.Conn, opts ...Option) (*Transactor, DBGetter) {
+	transactor := newTransactor(db, opts)
 
-		return db
-	}
+	// A connection can't be exhausted since using it concurrently fails immediately
+	transactor.core.SetPoolExhausted(func() bool {
+		return false
+	})
 
-	dbGetter := func(ctx context.Context) DB {
-		if tx := txFromContext(ctx); tx != nil {
-			return tx
-		}
+	return transactor, transactor.dbGetter(db)
+}
 
-		return db
-	}
+func NewTransactorFromPool(pool *pgxpool.Pool, opts ...Option) (*Transactor, DBGetter) {
+	transactor := newTransactor(pool, opts)
 
-	return &Transactor{
-		pgxTxGetter,
-	}, dbGetter
+	transactor.core.SetPoolExhausted(func() bool {
+		stat := pool.Stat()
+		return stat.AcquiredConns() >= stat.MaxConns()
+	})
+
+	return transactor, transactor.dbGetter(pool)
 }
 
-func NewTransactorFromPool(pool *pgxpool.Pool) (*Transactor, DBGetter) {
-	pgxTxGetter := func(ctx context.Context) pgxDB {
-		if tx := txFromContext(ctx); tx != nil {
-			return tx
-		}
+func newTransactor(db pgxDB, opts []Option) *Transactor {
+	transactor := &Transactor{}
+	transactor.core = core.NewTransactor(core.Driver[pgxDB, pgx.Tx]{
+		DB: func(ctx context.Context) pgxDB {
+			if tx := txFromContext(ctx); tx != nil {
+				return tx
+			}
+
+			return db
+		},
+		Begin: func(ctx context.Context, db pgxDB) (pgx.Tx, error) {
+			return db.Begin(ctx) //nolint:wrapcheck
+		},
+		Nest:      transactor.nest,
+		ErrTxDone: pgx.ErrTxClosed,
+	})
+	for _, opt := range opts {
+		opt(transactor)
+	}
 
-		return pool
+	if transactor.savepointPrefix == "" {
+		transactor.savepointPrefix = DefaultSavepointPrefix
+	}
+	if err := core.ValidateSavepointPrefix(transactor.savepointPrefix); err != nil {
+		panic(err)
 	}
 
-	dbGetter := func(ctx context.Context) DB {
+	return transactor
+}
+
+func (t *Transactor) dbGetter(db DB) DBGetter {
+	return func(ctx context.Context) DB {
 		if tx := txFromContext(ctx); tx != nil {
-			return tx
+			return t.concurrencySafeDB(ctx, t.strictDB(ctx, t.statementSavepointsDB(tx)))
 		}
 
-		return pool
+		return t.deadlockWatchingDB(db)
 	}
-
-	return &Transactor{
-		pgxTxGetter,
-	}, dbGetter
 }
 
-type (
-	pgxTxGetter func(context.Context) pgxDB
-)
+// Option configures a [Transactor].
+type Option func(*Transactor)
 
 type Transactor struct {
-	pgxTxGetter
+	core *core.Transactor[pgxDB, pgx.Tx]
+
+	concurrencySafe     bool
+	statementSavepoints bool
+	cockroachDB         bool
+	sessionSettings     SessionSettings
+	savepointPrefix     string
 }
 
 func (t *Transactor) WithinTransaction(ctx context.Context, txFunc func(context.Context) error) error {
-	db := t.pgxTxGetter(ctx)
-
-	tx, err := db.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer func() {
-		_ = tx.Rollback(ctx) // If rollback fails, there's nothing to do, the transaction will expire by itself
-	}()
+	return t.core.WithinTransaction(ctx, txFunc) //nolint:wrapcheck
+}
 
-	txCtx := txToContext(ctx, tx)
+// Shutdown gracefully shuts down the transactor.
+// New outermost transactions are refused with [ErrShuttingDown], while the running ones can still use nested transactions.
+// Shutdown waits for the running transactions to finish. If the context expires first, the remaining transactions are
+// aborted by canceling their context, and Shutdown returns how many were aborted along with the context error, without
+// waiting for their rollback.
+// It's meant to be called before closing the [pgx.Conn] or [pgxpool.Pool].
+func (t *Transactor) Shutdown(ctx context.Context) (int, error) {
+	return t.core.Shutdown(ctx) //nolint:wrapcheck
+}
 
-	if err := txFunc(txCtx); err != nil {
-		return err
-	}
+func IsWithinTransaction(ctx context.Context) bool {
+	return core.IsWithinTransaction[pgxDB](ctx)
+}
 
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+// WithoutTransaction returns a copy of the context without the current transaction, if any.
+// The DB handler returned by the [DBGetter] for this context is the original DB, and [IsWithinTransaction] reports false.
+// It's meant for work that must not be part of the current transaction: the state of the transaction, such as its lock
+// or its read-only flag, is removed as well. This context is still canceled along with ctx, when the transaction is
+// done or aborted by Shutdown, so use [DetachedContext] for work that outlives the transaction.
+func WithoutTransaction(ctx context.Context) context.Context {
+	// The fake transactions are left as well, so that the TxGetter fails as with the real transactor
+	if isWithinFakeTransaction(ctx) {
+		ctx = context.WithValue(ctx, fakeTransactionKey{}, nil)
 	}
 
-	return nil
+	return core.WithoutTransaction[pgxDB](ctx)
 }
 
-func IsWithinTransaction(ctx context.Context) bool {
-	return ctx.Value(transactorKey{}) != nil
+// DetachedContext returns a copy of the context without the current transaction, and that is not canceled when ctx is canceled.
+// It's meant for background work spawned from within a transaction, such as:
+//
+//	go doSomething(DetachedContext(ctx))
+func DetachedContext(ctx context.Context) context.Context {
+	return WithoutTransaction(context.WithoutCancel(ctx))
 }
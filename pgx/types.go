@@ -3,6 +3,7 @@ package pgx
 import (
 	"context"
 
+	"github.com/Thiht/transactor/internal/core"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -36,21 +37,12 @@ var (
 	_ pgxDB = &pgxpool.Tx{}
 )
 
-type (
-	transactorKey struct{}
-	// DBGetter is used to get the current DB handler from the context.
-	// It returns the current transaction if there is one, otherwise it will return the original DB.
-	DBGetter func(context.Context) DB
-)
-
-func txToContext(ctx context.Context, tx pgx.Tx) context.Context {
-	return context.WithValue(ctx, transactorKey{}, tx)
-}
+// DBGetter is used to get the current DB handler from the context.
+// It returns the current transaction if there is one, otherwise it will return the original DB.
+type DBGetter func(context.Context) DB
 
 func txFromContext(ctx context.Context) pgx.Tx {
-	if tx, ok := ctx.Value(transactorKey{}).(pgx.Tx); ok {
-		return tx
-	}
-
-	return nil
+	db, _ := core.TxFromContext[pgxDB](ctx)
+	tx, _ := db.(pgx.Tx)
+	return tx
 }
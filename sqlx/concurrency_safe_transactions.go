@@ -0,0 +1,210 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jmoiron/sqlx"
+)
+
+// WithConcurrencySafeTransactions makes the DB handler returned by the [DBGetter] within a transaction safe for concurrent use,
+// for example by goroutines of an errgroup started in the callback of WithinTransaction.
+// The statements are serialized on the transaction, and the results of the queries are read entirely before being returned
+// so that the connection is free for the next statement: they're held in memory, so large results should be avoided. The [sql.Stmt], [sqlx.Stmt] and [sqlx.NamedStmt] returned by the
+// Prepare methods are not serialized.
+// Nested transactions must not be started concurrently.
+func WithConcurrencySafeTransactions() Option {
+	return func(t *Transactor) {
+		t.concurrencySafe = true
+		t.core.SetConcurrencySafe()
+	}
+}
+
+// concurrencySafeDB returns a concurrency safe DB handler for the transaction, if enabled.
+func (t *Transactor) concurrencySafeDB(ctx context.Context, tx DB) DB {
+	if !t.concurrencySafe {
+		return tx
+	}
+
+	lock := core.TxLockFromContext(ctx)
+	if lock == nil {
+		return tx
+	}
+
+	return &concurrencySafeTx{tx: tx, lock: lock, buffer: t.buffer}
+}
+
+// concurrencySafeTx is a DB handler serializing the statements executed on a shared transaction.
+type concurrencySafeTx struct {
+	tx     DB
+	lock   *sync.Mutex
+	buffer *sqlx.DB
+}
+
+func (db *concurrencySafeTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.tx.ExecContext(ctx, query, args...) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.tx.PrepareContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.buffer.QueryContext(core.BufferedRowsToContext(ctx, rows), "") //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		rows = core.FailedRows(err)
+	}
+
+	return db.buffer.QueryRowContext(core.BufferedRowsToContext(ctx, rows), "")
+}
+
+func (db *concurrencySafeTx) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *concurrencySafeTx) Prepare(query string) (*sql.Stmt, error) {
+	return db.PrepareContext(context.Background(), query)
+}
+
+func (db *concurrencySafeTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *concurrencySafeTx) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+func (db *concurrencySafeTx) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return sqlx.GetContext(ctx, db, dest, query, args...) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) MustExecContext(ctx context.Context, query string, args ...any) sql.Result {
+	return sqlx.MustExecContext(ctx, db, query, args...)
+}
+
+func (db *concurrencySafeTx) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	return sqlx.NamedExecContext(ctx, db, query, arg) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.tx.PrepareNamedContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.tx.PreparexContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		rows = core.FailedRows(err)
+	}
+
+	return db.buffer.QueryRowxContext(core.BufferedRowsToContext(ctx, rows), "")
+}
+
+func (db *concurrencySafeTx) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.buffer.QueryxContext(core.BufferedRowsToContext(ctx, rows), "") //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return sqlx.SelectContext(ctx, db, dest, query, args...) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) Get(dest any, query string, args ...any) error {
+	return db.GetContext(context.Background(), dest, query, args...)
+}
+
+func (db *concurrencySafeTx) MustExec(query string, args ...any) sql.Result {
+	return db.MustExecContext(context.Background(), query, args...)
+}
+
+func (db *concurrencySafeTx) NamedExec(query string, arg any) (sql.Result, error) {
+	return db.NamedExecContext(context.Background(), query, arg)
+}
+
+func (db *concurrencySafeTx) NamedQuery(query string, arg any) (*sqlx.Rows, error) {
+	return sqlx.NamedQueryContext(context.Background(), db, query, arg) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	return db.PrepareNamedContext(context.Background(), query)
+}
+
+func (db *concurrencySafeTx) Preparex(query string) (*sqlx.Stmt, error) {
+	return db.PreparexContext(context.Background(), query)
+}
+
+func (db *concurrencySafeTx) QueryRowx(query string, args ...any) *sqlx.Row {
+	return db.QueryRowxContext(context.Background(), query, args...)
+}
+
+func (db *concurrencySafeTx) Queryx(query string, args ...any) (*sqlx.Rows, error) {
+	return db.QueryxContext(context.Background(), query, args...)
+}
+
+func (db *concurrencySafeTx) Select(dest any, query string, args ...any) error {
+	return db.SelectContext(context.Background(), dest, query, args...)
+}
+
+func (db *concurrencySafeTx) Rebind(query string) string {
+	return db.tx.Rebind(query)
+}
+
+func (db *concurrencySafeTx) BindNamed(query string, arg any) (string, []any, error) {
+	return db.tx.BindNamed(query, arg) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) DriverName() string {
+	return db.tx.DriverName()
+}
+
+// query executes a query and reads its results entirely.
+func (db *concurrencySafeTx) query(ctx context.Context, query string, args ...any) (*core.BufferedRows, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	rows, err := db.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return core.ReadRows(rows)
+}
+
+// newBufferDB returns a DB handler replaying the buffered rows found in the context of its queries,
+// as the [sqlx.Rows] and [sqlx.Row] types can't be created otherwise. The rows are scanned with the mapper of the given DB.
+func newBufferDB(db *sqlx.DB) *sqlx.DB {
+	buffer := sqlx.NewDb(core.NewBufferDB(), db.DriverName())
+	buffer.Mapper = db.Mapper
+
+	return buffer
+}
@@ -0,0 +1,190 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	// DeadlockError describes a goroutine waiting for a connection of an exhausted pool while it's holding a transaction,
+	// which can only be released by the goroutine itself.
+	DeadlockError = core.DeadlockError
+
+	// DeadlockDetection configures the pool deadlock detection.
+	DeadlockDetection = core.DeadlockDetection
+)
+
+// WithDeadlockDetection enables a debug mode detecting the goroutines that wait for a connection of an exhausted pool
+// while holding a transaction, for example by using the DB handler returned by the [DBGetter] with a context that is not
+// the one of their transaction, or by starting a new outermost transaction from within a transaction.
+// Only the context-aware methods of the DB handler are watched, since the others can't be canceled, and only until
+// they acquire their connection: a query running for longer than the threshold is not reported.
+// The results of the queries are read entirely before being returned, so that their connection is released at once.
+// It relies on stack traces to identify the goroutines, so it should only be used in development.
+// It panics if [DeadlockDetection.Threshold] is not positive.
+func WithDeadlockDetection(config DeadlockDetection) Option {
+	return func(t *Transactor) {
+		t.core.SetDeadlockDetection(config)
+	}
+}
+
+// deadlockWatchingDB returns a DB handler watching for deadlocks.
+// It returns the DB handler as is if the deadlock detection is disabled.
+func (t *Transactor) deadlockWatchingDB(db *sqlx.DB) DB {
+	detector := t.core.DeadlockDetector()
+	if detector == nil {
+		return db
+	}
+
+	return &deadlockWatchingDB{DB: db, detector: detector, buffer: t.buffer}
+}
+
+// deadlockWatchingDB runs the statements on a dedicated connection, so that the watch stops as soon as it's acquired.
+// The results of the queries are read entirely, so that their connection is closed right away.
+type deadlockWatchingDB struct {
+	*sqlx.DB
+	detector *core.DeadlockDetector
+	buffer   *sqlx.DB
+}
+
+// conn acquires a connection, watching for deadlocks until it's acquired.
+func (db *deadlockWatchingDB) conn(ctx context.Context) (*sqlx.Conn, error) {
+	watchCtx, stop := db.detector.Watch(ctx)
+	conn, err := db.DB.Connx(watchCtx)
+	stop()
+
+	return conn, core.DeadlockCause(watchCtx, err)
+}
+
+func (db *deadlockWatchingDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	conn, err := db.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.ExecContext(ctx, query, args...) //nolint:wrapcheck
+}
+
+// PrepareContext prepares the statement on the DB rather than on a dedicated connection, so that it's not bound to it.
+// The watch then lasts until the statement is prepared.
+func (db *deadlockWatchingDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	watchCtx, stop := db.detector.Watch(ctx)
+	defer stop()
+
+	stmt, err := db.DB.PrepareContext(watchCtx, query)
+	return stmt, core.DeadlockCause(watchCtx, err)
+}
+
+func (db *deadlockWatchingDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.buffer.QueryContext(core.BufferedRowsToContext(ctx, rows), "") //nolint:wrapcheck
+}
+
+func (db *deadlockWatchingDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		rows = core.FailedRows(err)
+	}
+
+	return db.buffer.QueryRowContext(core.BufferedRowsToContext(ctx, rows), "")
+}
+
+func (db *deadlockWatchingDB) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	conn, err := db.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.GetContext(ctx, dest, query, args...) //nolint:wrapcheck
+}
+
+func (db *deadlockWatchingDB) MustExecContext(ctx context.Context, query string, args ...any) sql.Result {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+func (db *deadlockWatchingDB) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	query, args, err := db.DB.BindNamed(query, arg)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.ExecContext(ctx, query, args...)
+}
+
+// PrepareNamedContext prepares the statement on the DB rather than on a dedicated connection, so that it's not bound to it.
+// The watch then lasts until the statement is prepared.
+func (db *deadlockWatchingDB) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	watchCtx, stop := db.detector.Watch(ctx)
+	defer stop()
+
+	stmt, err := db.DB.PrepareNamedContext(watchCtx, query)
+	return stmt, core.DeadlockCause(watchCtx, err)
+}
+
+// PreparexContext prepares the statement on the DB rather than on a dedicated connection, so that it's not bound to it.
+// The watch then lasts until the statement is prepared.
+func (db *deadlockWatchingDB) PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	watchCtx, stop := db.detector.Watch(ctx)
+	defer stop()
+
+	stmt, err := db.DB.PreparexContext(watchCtx, query)
+	return stmt, core.DeadlockCause(watchCtx, err)
+}
+
+func (db *deadlockWatchingDB) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		rows = core.FailedRows(err)
+	}
+
+	return db.buffer.QueryRowxContext(core.BufferedRowsToContext(ctx, rows), "")
+}
+
+func (db *deadlockWatchingDB) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.buffer.QueryxContext(core.BufferedRowsToContext(ctx, rows), "") //nolint:wrapcheck
+}
+
+// query executes a query on a dedicated connection and reads its results entirely, before closing the connection.
+func (db *deadlockWatchingDB) query(ctx context.Context, query string, args ...any) (*core.BufferedRows, error) {
+	conn, err := db.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return core.ReadRows(rows)
+}
+
+func (db *deadlockWatchingDB) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	conn, err := db.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.SelectContext(ctx, dest, query, args...) //nolint:wrapcheck
+}
@@ -0,0 +1,54 @@
+package sqlx
+
+import (
+	"context"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	// FirebirdTransaction configures the parameters of the Firebird transactions, see [WithFirebird].
+	// Its TxOptions method returns the matching transaction options, and SetTransaction the SET TRANSACTION statement.
+	FirebirdTransaction = core.FirebirdTransaction
+
+	// FirebirdIsolation is the isolation level of a Firebird transaction.
+	FirebirdIsolation = core.FirebirdIsolation
+)
+
+// ErrUnsupportedTxOptions is returned when transaction parameters can't be expressed with the [sql.TxOptions] of database/sql.
+var ErrUnsupportedTxOptions = core.ErrUnsupportedTxOptions
+
+const (
+	FirebirdReadCommitted                = core.FirebirdReadCommitted
+	FirebirdReadCommittedNoRecordVersion = core.FirebirdReadCommittedNoRecordVersion
+	FirebirdReadConsistency              = core.FirebirdReadConsistency
+	FirebirdSnapshot                     = core.FirebirdSnapshot
+	FirebirdSnapshotTableStability       = core.FirebirdSnapshotTableStability
+)
+
+// WithFirebird begins the outermost transactions with the given Firebird transaction parameters.
+// They're passed to the driver as [sql.TxOptions], see [FirebirdTransaction.TxOptions]: WithFirebird panics with
+// [ErrUnsupportedTxOptions] if the driver can't begin a transaction with them, for example with a lock timeout.
+// The transactions begun within a context returned by [WithReadOnly] are READ ONLY, with the same parameters. They fail
+// with [ErrUnsupportedTxOptions] if the isolation level isn't READ COMMITTED RECORD_VERSION.
+// It's meant to be used with [NestedTransactionsFirebird] and github.com/nakagami/firebirdsql.
+func WithFirebird(config FirebirdTransaction) Option {
+	return func(t *Transactor) {
+		if _, err := config.TxOptions(); err != nil {
+			panic(err)
+		}
+
+		t.firebird = &config
+	}
+}
+
+// beginFirebird begins a transaction with the transaction options of the parameters.
+func beginFirebird(ctx context.Context, db sqlxDB, config FirebirdTransaction) (*sqlx.Tx, error) {
+	opts, err := config.TxOptions()
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.BeginTxx(ctx, opts) //nolint:wrapcheck
+}
@@ -2,9 +2,14 @@ module github.com/Thiht/transactor/sqlx
 
 go 1.24.0
 
-require github.com/jmoiron/sqlx v1.4.0
+require (
+	github.com/Thiht/transactor v0.0.0
+	github.com/jmoiron/sqlx v1.4.0
+)
 
 require (
 	filippo.io/edwards25519 v1.2.0 // indirect
 	github.com/go-sql-driver/mysql v1.9.3 // indirect
 )
+
+replace github.com/Thiht/transactor => ../
@@ -0,0 +1,207 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrImplicitCommit is returned by the DB handler returned by the [DBGetter] within a transaction,
+// when a statement would implicitly commit the transaction, see [WithImplicitCommitGuard].
+var ErrImplicitCommit = core.ErrImplicitCommit
+
+// ImplicitCommits lists the statements that implicitly commit the current transaction on a database, see [WithImplicitCommitGuard].
+type ImplicitCommits = core.ImplicitCommits
+
+// MySQLImplicitCommits returns the statements implicitly committing the transaction on MySQL and MariaDB:
+// the DDL statements except the temporary tables, LOCK TABLES, and the administration statements.
+// The content of the executable comments, such as /*!50100 CREATE TABLE ... */, is checked as well.
+func MySQLImplicitCommits() *ImplicitCommits {
+	return core.MySQLImplicitCommits()
+}
+
+// OracleImplicitCommits returns the statements implicitly committing the transaction on Oracle: the DDL statements.
+func OracleImplicitCommits() *ImplicitCommits {
+	return core.OracleImplicitCommits()
+}
+
+// WithImplicitCommitGuard rejects the statements that implicitly commit the current transaction, such as the DDL
+// statements on MySQL and Oracle, when they're executed by the DB handler returned by the [DBGetter] within a
+// transaction. They fail with [ErrImplicitCommit] without being executed, instead of silently committing the changes
+// made so far and running the rest of the transaction outside of any transaction.
+// The statements are recognized by their leading keywords, after the comments.
+func WithImplicitCommitGuard(statements *ImplicitCommits) Option {
+	return func(t *Transactor) {
+		t.implicitCommits = statements
+	}
+}
+
+// implicitCommitGuardDB returns a DB handler rejecting the statements implicitly committing the transaction, if enabled.
+func (t *Transactor) implicitCommitGuardDB(tx DB) DB {
+	if t.implicitCommits == nil {
+		return tx
+	}
+
+	return &implicitCommitGuardTx{DB: tx, statements: t.implicitCommits, buffer: t.buffer}
+}
+
+// implicitCommitGuardTx is a DB handler rejecting the statements implicitly committing the transaction.
+type implicitCommitGuardTx struct {
+	DB
+	statements *core.ImplicitCommits
+	buffer     *sqlx.DB
+}
+
+func (db *implicitCommitGuardTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := db.statements.Check(query); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.DB.ExecContext(ctx, query, args...) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if err := db.statements.Check(query); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.DB.PrepareContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := db.statements.Check(query); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.DB.QueryContext(ctx, query, args...) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if err := db.statements.Check(query); err != nil {
+		return db.buffer.QueryRowContext(core.BufferedRowsToContext(ctx, core.FailedRows(err)), "")
+	}
+
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (db *implicitCommitGuardTx) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *implicitCommitGuardTx) Prepare(query string) (*sql.Stmt, error) {
+	return db.PrepareContext(context.Background(), query)
+}
+
+func (db *implicitCommitGuardTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *implicitCommitGuardTx) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+func (db *implicitCommitGuardTx) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	if err := db.statements.Check(query); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return db.DB.GetContext(ctx, dest, query, args...) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) MustExecContext(ctx context.Context, query string, args ...any) sql.Result {
+	if err := db.statements.Check(query); err != nil {
+		panic(err)
+	}
+
+	return db.DB.MustExecContext(ctx, query, args...)
+}
+
+func (db *implicitCommitGuardTx) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	if err := db.statements.Check(query); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.DB.NamedExecContext(ctx, query, arg) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	if err := db.statements.Check(query); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.DB.PrepareNamedContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	if err := db.statements.Check(query); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.DB.PreparexContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	if err := db.statements.Check(query); err != nil {
+		return db.buffer.QueryRowxContext(core.BufferedRowsToContext(ctx, core.FailedRows(err)), "")
+	}
+
+	return db.DB.QueryRowxContext(ctx, query, args...)
+}
+
+func (db *implicitCommitGuardTx) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	if err := db.statements.Check(query); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.DB.QueryxContext(ctx, query, args...) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	if err := db.statements.Check(query); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return db.DB.SelectContext(ctx, dest, query, args...) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) Get(dest any, query string, args ...any) error {
+	return db.GetContext(context.Background(), dest, query, args...)
+}
+
+func (db *implicitCommitGuardTx) MustExec(query string, args ...any) sql.Result {
+	return db.MustExecContext(context.Background(), query, args...)
+}
+
+func (db *implicitCommitGuardTx) NamedExec(query string, arg any) (sql.Result, error) {
+	return db.NamedExecContext(context.Background(), query, arg)
+}
+
+func (db *implicitCommitGuardTx) NamedQuery(query string, arg any) (*sqlx.Rows, error) {
+	if err := db.statements.Check(query); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.DB.NamedQuery(query, arg) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	return db.PrepareNamedContext(context.Background(), query)
+}
+
+func (db *implicitCommitGuardTx) Preparex(query string) (*sqlx.Stmt, error) {
+	return db.PreparexContext(context.Background(), query)
+}
+
+func (db *implicitCommitGuardTx) QueryRowx(query string, args ...any) *sqlx.Row {
+	return db.QueryRowxContext(context.Background(), query, args...)
+}
+
+func (db *implicitCommitGuardTx) Queryx(query string, args ...any) (*sqlx.Rows, error) {
+	return db.QueryxContext(context.Background(), query, args...)
+}
+
+func (db *implicitCommitGuardTx) Select(dest any, query string, args ...any) error {
+	return db.SelectContext(context.Background(), dest, query, args...)
+}
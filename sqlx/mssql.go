@@ -0,0 +1,42 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MSSQL configures the transactions of a Microsoft SQL Server database, see [WithMSSQL].
+type MSSQL struct {
+	// XactAbort executes SET XACT_ABORT ON when beginning the outermost transactions: any error then dooms the
+	// transaction, instead of only aborting the failed statement. The setting lasts until the connection is reset
+	// by the driver, when it's reused from the pool.
+	XactAbort bool
+
+	// Snapshot begins the outermost transactions with the SNAPSHOT isolation level.
+	// It requires ALLOW_SNAPSHOT_ISOLATION to be enabled on the database.
+	Snapshot bool
+}
+
+// WithMSSQL begins the outermost transactions with the given SQL Server settings.
+// It's meant to be used with [NestedTransactionsMSSQL], whose nested rollbacks fail with [ErrTransactionDoomed]
+// once an error doomed the transaction.
+func WithMSSQL(config MSSQL) Option {
+	return func(t *Transactor) {
+		t.mssql = &config
+	}
+}
+
+// setXactAbort applies SET XACT_ABORT ON to a transaction, if enabled.
+func (s *MSSQL) setXactAbort(ctx context.Context, tx *sqlx.Tx) error {
+	if !s.XactAbort {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "SET XACT_ABORT ON"); err != nil {
+		return fmt.Errorf("failed to set XACT_ABORT: %w", err)
+	}
+
+	return nil
+}
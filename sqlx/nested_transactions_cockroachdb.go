@@ -0,0 +1,26 @@
+package sqlx
+
+import (
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jmoiron/sqlx"
+)
+
+// NestedTransactionsCockroachDB is a nested transactions implementation for CockroachDB.
+// The outermost transactions follow the client-side retry protocol of CockroachDB: they begin with
+// SAVEPOINT cockroach_restart, and when they fail with a retryable error (SQLSTATE 40001), they're rolled back to it
+// and the callback of WithinTransaction runs again, until RELEASE SAVEPOINT cockroach_restart succeeds.
+// The callback must therefore be safe to run several times. The nested transactions use savepoints.
+func NestedTransactionsCockroachDB(db sqlxDB, tx *sqlx.Tx) (sqlxDB, sqlxTx) {
+	newDB, currentTX := NestedTransactionsSavepoints(db, tx)
+	if _, outermost := db.(*sqlx.DB); outermost {
+		return newDB, restartableTx{sqlxTx: currentTX, Restarter: core.CockroachRestart[*sqlx.Tx]{Tx: tx, Exec: execStatement}}
+	}
+
+	return newDB, currentTX
+}
+
+// restartableTx is an outermost transaction restarted by the core when it fails with a retryable error.
+type restartableTx struct {
+	sqlxTx
+	core.Restarter
+}
@@ -0,0 +1,8 @@
+package sqlx
+
+import "github.com/jmoiron/sqlx"
+
+// NestedTransactionsDB2 is a nested transactions implementation using IBM Db2 savepoints.
+func NestedTransactionsDB2(db sqlxDB, tx *sqlx.Tx) (sqlxDB, sqlxTx) {
+	return NestedTransactionsWith(DB2Strategy())(db, tx)
+}
@@ -0,0 +1,9 @@
+package sqlx
+
+import "github.com/jmoiron/sqlx"
+
+// NestedTransactionsFirebird is a nested transactions implementation using Firebird savepoints.
+// The transaction parameters can be set with [WithFirebird].
+func NestedTransactionsFirebird(db sqlxDB, tx *sqlx.Tx) (sqlxDB, sqlxTx) {
+	return NestedTransactionsWith(FirebirdStrategy())(db, tx)
+}
@@ -2,62 +2,34 @@ package sqlx
 
 import (
 	"context"
-	"database/sql"
-	"fmt"
-	"strconv"
-	"sync/atomic"
 
+	"github.com/Thiht/transactor/internal/core"
 	"github.com/jmoiron/sqlx"
 )
 
+// ErrTransactionDoomed is returned by WithinTransaction when committing an outermost transaction that an error doomed,
+// with [NestedTransactionsMSSQL]: one of its nested transactions couldn't be rolled back to its savepoint,
+// so the outermost transaction is rolled back instead.
+var ErrTransactionDoomed = core.ErrTransactionDoomed
+
 // NestedTransactionsMSSQL is a nested transactions implementation using Microsoft SQL Server savepoints.
+// Before rolling back to a savepoint, it checks that the transaction isn't doomed with XACT_STATE(),
+// since SQL Server can't roll back a doomed transaction to a savepoint.
+// When the transaction is doomed, the nested WithinTransaction fails with [ErrTransactionDoomed] along with the error
+// of its callback, and committing the outermost transaction rolls it back and fails with [ErrTransactionDoomed].
 func NestedTransactionsMSSQL(db sqlxDB, tx *sqlx.Tx) (sqlxDB, sqlxTx) {
-	switch typedDB := db.(type) {
-	case *sqlx.DB:
-		return &nestedTransactionMSSQL{Tx: tx}, tx
-
-	case *nestedTransactionMSSQL:
-		nestedTransaction := &nestedTransactionMSSQL{
-			Tx:    tx,
-			depth: typedDB.depth + 1,
-		}
-		return nestedTransaction, nestedTransaction
-
-	default:
-		panic("unsupported type")
-	}
-}
-
-type nestedTransactionMSSQL struct {
-	*sqlx.Tx
-	depth int64
-	done  atomic.Bool
-}
-
-func (t *nestedTransactionMSSQL) BeginTxx(ctx context.Context, _ *sql.TxOptions) (*sqlx.Tx, error) {
-	if _, err := t.ExecContext(ctx, "SAVE TRANSACTION sp_"+strconv.FormatInt(t.depth+1, 10)); err != nil {
-		return nil, fmt.Errorf("failed to create savepoint: %w", err)
+	if _, outermost := db.(*sqlx.DB); outermost {
+		strategy := core.NewMSSQLStrategy(execStatement, queryInt)
+		newDB, _ := NestedTransactionsWith(strategy)(db, tx)
+		return newDB, rollbackOnlyTx{Tx: tx, checker: strategy}
 	}
 
-	return t.Tx, nil
+	// The nested transactions reuse the strategy of their outermost transaction
+	return NestedTransactionsWith(nil)(db, tx)
 }
 
-func (t *nestedTransactionMSSQL) Commit() error {
-	if !t.done.CompareAndSwap(false, true) {
-		return sql.ErrTxDone
-	}
-
-	return nil
-}
-
-func (t *nestedTransactionMSSQL) Rollback() error {
-	if !t.done.CompareAndSwap(false, true) {
-		return sql.ErrTxDone
-	}
-
-	if _, err := t.Exec("ROLLBACK TRANSACTION sp_" + strconv.FormatInt(t.depth, 10)); err != nil {
-		return fmt.Errorf("failed to rollback to savepoint: %w", err)
-	}
-
-	return nil
+func queryInt(ctx context.Context, tx *sqlx.Tx, query string) (int, error) {
+	var value int
+	err := tx.QueryRowContext(ctx, query).Scan(&value)
+	return value, err //nolint:wrapcheck
 }
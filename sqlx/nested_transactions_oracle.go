@@ -1,63 +1,8 @@
 package sqlx
 
-import (
-	"context"
-	"database/sql"
-	"fmt"
-	"strconv"
-	"sync/atomic"
-
-	"github.com/jmoiron/sqlx"
-)
+import "github.com/jmoiron/sqlx"
 
 // NestedTransactionsOracle is a nested transactions implementation using Oracle savepoints.
 func NestedTransactionsOracle(db sqlxDB, tx *sqlx.Tx) (sqlxDB, sqlxTx) {
-	switch typedDB := db.(type) {
-	case *sqlx.DB:
-		return &nestedTransactionOracle{Tx: tx}, tx
-
-	case *nestedTransactionOracle:
-		nestedTransaction := &nestedTransactionOracle{
-			Tx:    tx,
-			depth: typedDB.depth + 1,
-		}
-		return nestedTransaction, nestedTransaction
-
-	default:
-		panic("unsupported type")
-	}
-}
-
-type nestedTransactionOracle struct {
-	*sqlx.Tx
-	depth int64
-	done  atomic.Bool
-}
-
-func (t *nestedTransactionOracle) BeginTxx(ctx context.Context, _ *sql.TxOptions) (*sqlx.Tx, error) {
-	if _, err := t.ExecContext(ctx, "SAVEPOINT sp_"+strconv.FormatInt(t.depth+1, 10)); err != nil {
-		return nil, fmt.Errorf("failed to create savepoint: %w", err)
-	}
-
-	return t.Tx, nil
-}
-
-func (t *nestedTransactionOracle) Commit() error {
-	if !t.done.CompareAndSwap(false, true) {
-		return sql.ErrTxDone
-	}
-
-	return nil
-}
-
-func (t *nestedTransactionOracle) Rollback() error {
-	if !t.done.CompareAndSwap(false, true) {
-		return sql.ErrTxDone
-	}
-
-	if _, err := t.Exec("ROLLBACK TO SAVEPOINT sp_" + strconv.FormatInt(t.depth, 10)); err != nil {
-		return fmt.Errorf("failed to rollback to savepoint: %w", err)
-	}
-
-	return nil
+	return NestedTransactionsWith(OracleStrategy())(db, tx)
 }
@@ -0,0 +1,53 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jmoiron/sqlx"
+)
+
+// OracleTransaction configures the SET TRANSACTION statement beginning the Oracle transactions, see [WithOracle].
+// Its SetTransaction method returns the statement.
+type OracleTransaction = core.OracleTransaction
+
+// WithOracle begins the outermost transactions with the given Oracle options, by executing SET TRANSACTION as their
+// first statement: the Oracle drivers don't support the read-only and isolation options of database/sql.
+// The transactions begun within a context returned by [WithReadOnly] are READ ONLY, with the same name.
+// WithOracle panics if the options are invalid, see [OracleTransaction.SetTransaction].
+// It's meant to be used with [NestedTransactionsOracle].
+func WithOracle(config OracleTransaction) Option {
+	return func(t *Transactor) {
+		if _, err := config.SetTransaction(); err != nil {
+			panic(err)
+		}
+
+		t.oracle = &config
+	}
+}
+
+// beginOracle begins a transaction with the SET TRANSACTION statement of the options.
+func beginOracle(ctx context.Context, db sqlxDB, config OracleTransaction) (*sqlx.Tx, error) {
+	statement, err := config.SetTransaction()
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return beginSetTransaction(ctx, db, statement)
+}
+
+// beginSetTransaction begins a transaction and executes the SET TRANSACTION statement as its first statement, if any.
+func beginSetTransaction(ctx context.Context, db sqlxDB, statement string) (*sqlx.Tx, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil || statement == "" {
+		return tx, err //nolint:wrapcheck
+	}
+
+	if _, err := tx.ExecContext(ctx, statement); err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to set transaction: %w", err), tx.Rollback())
+	}
+
+	return tx, nil
+}
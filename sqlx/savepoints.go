@@ -0,0 +1,70 @@
+package sqlx
+
+import (
+	"context"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+var (
+	// ErrSavepointsNotSupported is returned when using a savepoint with a nested transactions strategy that doesn't support them.
+	ErrSavepointsNotSupported = core.ErrSavepointsNotSupported
+
+	// ErrInvalidSavepointName is returned when a savepoint name is not a valid SQL identifier.
+	ErrInvalidSavepointName = core.ErrInvalidSavepointName
+)
+
+// SavepointError is returned when a savepoint can't be created, released, or rolled back to.
+// The transaction should then be considered unusable.
+type SavepointError = core.SavepointError
+
+// WithinSavepoint executes the given function within a named savepoint of the current transaction,
+// using the SQL of the nested transactions strategy.
+// If the function fails, its changes are rolled back to the savepoint and its error is returned as is, so that it can be
+// inspected and swallowed while the transaction stays usable. If the savepoint itself fails, a [*SavepointError] is returned.
+// The name must not exceed the limit of the strategy, 32 characters with SQL Server for example, or it fails with
+// [ErrInvalidSavepointName] before creating the savepoint.
+// Unlike a nested WithinTransaction, the function runs in the current transaction, without a new transaction scope.
+func (t *Transactor) WithinSavepoint(ctx context.Context, name string, fn func(context.Context) error) error {
+	fnErr, err := t.withinSavepoint(ctx, name, fn)
+	if err != nil {
+		return err
+	}
+
+	return fnErr
+}
+
+// TrySavepoint executes the given function within a named savepoint of the current transaction, like [Transactor.WithinSavepoint].
+// If the function fails with an error for which recoverable returns true, its changes are rolled back to the savepoint
+// and TrySavepoint returns false without error, so that an alternative can be tried in the same transaction.
+func (t *Transactor) TrySavepoint(ctx context.Context, name string, fn func(context.Context) error, recoverable func(error) bool) (bool, error) {
+	fnErr, err := t.withinSavepoint(ctx, name, fn)
+	switch {
+	case err != nil:
+		return false, err
+	case fnErr == nil:
+		return true, nil
+	case recoverable(fnErr):
+		return false, nil
+	default:
+		return false, fnErr
+	}
+}
+
+// withinSavepoint returns the error of the function separately from the error of the savepoint.
+func (t *Transactor) withinSavepoint(ctx context.Context, name string, fn func(context.Context) error) (error, error) {
+	if !IsWithinTransaction(ctx) {
+		return nil, ErrNoTransaction
+	}
+
+	if err := core.ValidateSavepointName(name); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	sp, ok := core.AsSavepointer(txFromContext(ctx))
+	if !ok {
+		return nil, ErrSavepointsNotSupported
+	}
+
+	return core.RunInSavepoint(ctx, sp, name, fn) //nolint:wrapcheck
+}
@@ -0,0 +1,38 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jmoiron/sqlx"
+)
+
+// SessionSettings returns the PostgreSQL settings to apply to the outermost transactions begun within a context,
+// for example the identity of the caller checked by the row-level security policies with current_setting.
+type SessionSettings = core.SessionSettings
+
+// WithSessionSettings applies the settings returned for the context of every outermost transaction right after
+// beginning it, with set_config(name, value, true). Like SET LOCAL, they only last until the end of the transaction,
+// so they're visible to its nested transactions and never leak to the other transactions of the connection.
+// With the retry protocol of CockroachDB, they're applied after SAVEPOINT cockroach_restart, and again after every restart.
+// It's meant to be used with PostgreSQL, and the drivers using the $1 placeholders.
+func WithSessionSettings(settings SessionSettings) Option {
+	return func(t *Transactor) {
+		t.sessionSettings = settings
+	}
+}
+
+// applySessionSettings applies the session settings of the context to an outermost transaction.
+func (t *Transactor) applySessionSettings(ctx context.Context, tx *sqlx.Tx) error {
+	query, args := core.SetConfig(t.sessionSettings(ctx))
+	if query == "" {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to apply session settings: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Thiht/transactor/internal/core"
+	"github.com/jmoiron/sqlx"
+)
+
+// WithStatementSavepoints wraps each statement executed by the DB handler returned by the [DBGetter] within a transaction
+// in an implicit savepoint, like the ON_ERROR_ROLLBACK setting of psql: a failed statement is rolled back to its savepoint,
+// so that the transaction stays usable and the error can be handled by the caller, for example a unique violation.
+// It requires a nested transactions strategy supporting savepoints, statements fail with [ErrSavepointsNotSupported] otherwise.
+//
+// It comes at a cost: every statement takes two additional round trips to create and release its savepoint,
+// and the results of the queries are read entirely in memory before being returned so that the savepoint can be released.
+// On PostgreSQL, every savepoint is also a subtransaction. The [sql.Stmt], [sqlx.Stmt] and [sqlx.NamedStmt] returned by
+// the Prepare methods are not wrapped.
+func WithStatementSavepoints() Option {
+	return func(t *Transactor) {
+		t.statementSavepoints = true
+	}
+}
+
+// statementSavepointsDB returns a DB handler wrapping the statements of the transaction in savepoints, if enabled.
+func (t *Transactor) statementSavepointsDB(tx sqlxDB) DB {
+	if !t.statementSavepoints {
+		return tx
+	}
+
+	sp, _ := core.AsSavepointer(tx)
+	return &statementSavepointsTx{tx: tx, sp: sp, name: core.NewSavepointNames(t.savepointPrefix).Statement(), buffer: t.buffer}
+}
+
+// statementSavepointsTx is a DB handler wrapping each statement in a savepoint.
+type statementSavepointsTx struct {
+	tx     DB
+	sp     core.Savepointer
+	name   string // The name of the savepoints, statements run one at a time on a transaction so a single name is enough
+	buffer *sqlx.DB
+}
+
+func (db *statementSavepointsTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+	err := db.statement(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = db.tx.ExecContext(ctx, query, args...)
+		return err //nolint:wrapcheck
+	})
+
+	return result, err
+}
+
+func (db *statementSavepointsTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return db.tx.PrepareContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *statementSavepointsTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.buffer.QueryContext(core.BufferedRowsToContext(ctx, rows), "") //nolint:wrapcheck
+}
+
+func (db *statementSavepointsTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		rows = core.FailedRows(err)
+	}
+
+	return db.buffer.QueryRowContext(core.BufferedRowsToContext(ctx, rows), "")
+}
+
+func (db *statementSavepointsTx) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *statementSavepointsTx) Prepare(query string) (*sql.Stmt, error) {
+	return db.PrepareContext(context.Background(), query)
+}
+
+func (db *statementSavepointsTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *statementSavepointsTx) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+func (db *statementSavepointsTx) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return sqlx.GetContext(ctx, db, dest, query, args...) //nolint:wrapcheck
+}
+
+func (db *statementSavepointsTx) MustExecContext(ctx context.Context, query string, args ...any) sql.Result {
+	return sqlx.MustExecContext(ctx, db, query, args...)
+}
+
+func (db *statementSavepointsTx) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	return sqlx.NamedExecContext(ctx, db, query, arg) //nolint:wrapcheck
+}
+
+func (db *statementSavepointsTx) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	return db.tx.PrepareNamedContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *statementSavepointsTx) PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	return db.tx.PreparexContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *statementSavepointsTx) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		rows = core.FailedRows(err)
+	}
+
+	return db.buffer.QueryRowxContext(core.BufferedRowsToContext(ctx, rows), "")
+}
+
+func (db *statementSavepointsTx) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.buffer.QueryxContext(core.BufferedRowsToContext(ctx, rows), "") //nolint:wrapcheck
+}
+
+func (db *statementSavepointsTx) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return sqlx.SelectContext(ctx, db, dest, query, args...) //nolint:wrapcheck
+}
+
+func (db *statementSavepointsTx) Get(dest any, query string, args ...any) error {
+	return db.GetContext(context.Background(), dest, query, args...)
+}
+
+func (db *statementSavepointsTx) MustExec(query string, args ...any) sql.Result {
+	return db.MustExecContext(context.Background(), query, args...)
+}
+
+func (db *statementSavepointsTx) NamedExec(query string, arg any) (sql.Result, error) {
+	return db.NamedExecContext(context.Background(), query, arg)
+}
+
+func (db *statementSavepointsTx) NamedQuery(query string, arg any) (*sqlx.Rows, error) {
+	return sqlx.NamedQueryContext(context.Background(), db, query, arg) //nolint:wrapcheck
+}
+
+func (db *statementSavepointsTx) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	return db.PrepareNamedContext(context.Background(), query)
+}
+
+func (db *statementSavepointsTx) Preparex(query string) (*sqlx.Stmt, error) {
+	return db.PreparexContext(context.Background(), query)
+}
+
+func (db *statementSavepointsTx) QueryRowx(query string, args ...any) *sqlx.Row {
+	return db.QueryRowxContext(context.Background(), query, args...)
+}
+
+func (db *statementSavepointsTx) Queryx(query string, args ...any) (*sqlx.Rows, error) {
+	return db.QueryxContext(context.Background(), query, args...)
+}
+
+func (db *statementSavepointsTx) Select(dest any, query string, args ...any) error {
+	return db.SelectContext(context.Background(), dest, query, args...)
+}
+
+func (db *statementSavepointsTx) Rebind(query string) string {
+	return db.tx.Rebind(query)
+}
+
+func (db *statementSavepointsTx) BindNamed(query string, arg any) (string, []any, error) {
+	return db.tx.BindNamed(query, arg) //nolint:wrapcheck
+}
+
+func (db *statementSavepointsTx) DriverName() string {
+	return db.tx.DriverName()
+}
+
+// query executes a query within a savepoint and reads its results entirely.
+func (db *statementSavepointsTx) query(ctx context.Context, query string, args ...any) (*core.BufferedRows, error) {
+	var buffered *core.BufferedRows
+	err := db.statement(ctx, func(ctx context.Context) error {
+		rows, err := db.tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		buffered, err = core.ReadRows(rows)
+		return err
+	})
+
+	return buffered, err
+}
+
+// statement executes a statement within a savepoint, rolled back to if the statement fails.
+func (db *statementSavepointsTx) statement(ctx context.Context, exec func(context.Context) error) error {
+	if db.sp == nil {
+		return ErrSavepointsNotSupported
+	}
+
+	execErr, err := core.RunInSavepoint(ctx, db.sp, db.name, exec)
+	if err != nil {
+		return err
+	}
+
+	return execErr
+}
@@ -0,0 +1,32 @@
+// Package strategytest implements a conformance test kit for the nested transactions strategies of [sqlxTransactor].
+package strategytest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Thiht/transactor/internal/core/strategytest"
+	sqlxTransactor "github.com/Thiht/transactor/sqlx"
+	"github.com/jmoiron/sqlx"
+)
+
+// Table is the table created by [Run] to check the changes made by the transactions.
+const Table = strategytest.Table
+
+// Run checks that the strategy implements the nested transactions on the database: the changes of the committed nested
+// transactions are kept until the outermost transaction commits, and the changes of the rolled back nested transactions
+// are discarded while the outermost transaction stays usable.
+// It creates the [Table] table, so the database must be dedicated to the test, and drops it once done.
+func Run(t *testing.T, db *sqlx.DB, strategy sqlxTransactor.NestedTransactionsStrategy) {
+	t.Helper()
+
+	transactor, dbGetter := sqlxTransactor.NewTransactor(db, sqlxTransactor.NestedTransactionsWith(strategy))
+	strategytest.Run(t, db.DB, strategytest.Transactor{
+		WithinTransaction: transactor.WithinTransaction,
+		WithinSavepoint:   transactor.WithinSavepoint,
+		Exec: func(ctx context.Context, query string) error {
+			_, err := dbGetter(ctx).ExecContext(ctx, query)
+			return err //nolint:wrapcheck
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package sqlx
+
+import (
+	"github.com/Thiht/transactor/internal/core"
+)
+
+// ErrSubtransactionBudgetExceeded is returned when creating a savepoint past the subtransaction budget,
+// with [SubtransactionsFail].
+var ErrSubtransactionBudgetExceeded = core.ErrSubtransactionBudgetExceeded
+
+type (
+	// SubtransactionBudget limits the number of savepoints created within an outermost transaction.
+	SubtransactionBudget = core.SubtransactionBudget
+
+	// SubtransactionOverflow is what happens to the savepoints created past the subtransaction budget.
+	SubtransactionOverflow = core.SubtransactionOverflow
+)
+
+const (
+	SubtransactionsWarn    = core.SubtransactionsWarn
+	SubtransactionsFail    = core.SubtransactionsFail
+	SubtransactionsFlatten = core.SubtransactionsFlatten
+)
+
+// WithSubtransactionBudget limits the number of savepoints created within an outermost transaction, 64 by default.
+// On PostgreSQL, a transaction with more than 64 subtransactions, even released ones, overflows the subtransactions
+// cache of its backend and slows down the whole database.
+// The savepoints of the nested transactions, of WithinSavepoint and TrySavepoint, and the statement savepoints are
+// counted once they're created, while the nested transactions of the strategies without savepoints aren't.
+// Past the budget, the savepoints are created anyway, fail, or are skipped and their statements run in the parent
+// transaction, depending on [SubtransactionBudget.Overflow].
+func WithSubtransactionBudget(config SubtransactionBudget) Option {
+	return func(t *Transactor) {
+		t.core.SetSubtransactionBudget(config)
+	}
+}
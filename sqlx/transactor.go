@@ -2,69 +2,209 @@ package sqlx
 
 import (
 	"context"
-	"fmt"
+	"database/sql"
+	"errors"
 
+	"github.com/Thiht/transactor/internal/core"
 	"github.com/jmoiron/sqlx"
 )
 
-func NewTransactor(db *sqlx.DB, nestedTransactionStrategy nestedTransactionsStrategy) (*Transactor, DBGetter) {
-	sqlDBGetter := func(ctx context.Context) sqlxDB {
-		if tx := txFromContext(ctx); tx != nil {
-			return tx
-		}
+func NewTransactor(db *sqlx.DB, nestedTransactionStrategy nestedTransactionsFunc, opts ...Option) (*Transactor, DBGetter) {
+	transactor := &Transactor{
+		nestedTransactionsFunc: nestedTransactionStrategy,
+	}
+	transactor.core = core.NewTransactor(core.Driver[sqlxDB, *sqlx.Tx]{
+		DB: func(ctx context.Context) sqlxDB {
+			if tx := txFromContext(ctx); tx != nil {
+				return tx
+			}
+
+			return db
+		},
+		Begin:     transactor.begin,
+		Nest:      transactor.nest,
+		ErrTxDone: sql.ErrTxDone,
+	})
+	for _, opt := range opts {
+		opt(transactor)
+	}
 
-		return db
+	if transactor.savepointPrefix == "" {
+		transactor.savepointPrefix = DefaultSavepointPrefix
+	}
+	if err := core.ValidateSavepointPrefix(transactor.savepointPrefix); err != nil {
+		panic(err)
+	}
+
+	transactor.core.SetPoolExhausted(func() bool {
+		stats := db.Stats()
+		return stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections
+	})
+
+	if transactor.concurrencySafe || transactor.statementSavepoints || transactor.implicitCommits != nil || transactor.core.DeadlockDetector() != nil {
+		transactor.buffer = newBufferDB(db)
 	}
 
 	dbGetter := func(ctx context.Context) DB {
 		if tx := txFromContext(ctx); tx != nil {
-			return tx
+			return transactor.implicitCommitGuardDB(transactor.concurrencySafeDB(ctx, transactor.strictDB(ctx, transactor.statementSavepointsDB(tx))))
 		}
 
-		return db
+		return transactor.deadlockWatchingDB(db)
 	}
 
-	return &Transactor{
-		sqlDBGetter,
-		nestedTransactionStrategy,
-	}, dbGetter
+	return transactor, dbGetter
 }
 
-type (
-	sqlxDBGetter               func(context.Context) sqlxDB
-	nestedTransactionsStrategy func(sqlxDB, *sqlx.Tx) (sqlxDB, sqlxTx)
-)
+type nestedTransactionsFunc func(sqlxDB, *sqlx.Tx) (sqlxDB, sqlxTx)
+
+// Option configures a [Transactor].
+type Option func(*Transactor)
 
 type Transactor struct {
-	sqlxDBGetter
-	nestedTransactionsStrategy
+	core *core.Transactor[sqlxDB, *sqlx.Tx]
+	nestedTransactionsFunc
+
+	savepointPrefix string
+	sqlite          *SQLite
+	mssql           *MSSQL
+	oracle          *OracleTransaction
+	firebird        *FirebirdTransaction
+	mysql           *MySQL
+	implicitCommits *core.ImplicitCommits
+	sessionSettings SessionSettings
+
+	concurrencySafe     bool
+	statementSavepoints bool
+	buffer              *sqlx.DB // Replays the query results read by the concurrency safe transactions, statement savepoints and deadlock detection, and the errors of the implicit commit guard
 }
 
 func (t *Transactor) WithinTransaction(ctx context.Context, txFunc func(context.Context) error) error {
-	currentDB := t.sqlxDBGetter(ctx)
+	return t.core.WithinTransaction(ctx, txFunc) //nolint:wrapcheck
+}
 
-	tx, err := currentDB.BeginTxx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+// nest applies the nested transactions strategy to a transaction begun by the core.
+func (t *Transactor) nest(db sqlxDB, tx *sqlx.Tx, outermost bool) (sqlxDB, core.Completer) {
+	newDB, currentTX := t.nestedTransactionsFunc(db, tx)
+	if named, ok := newDB.(namedSavepoints); ok && outermost {
+		named.SetSavepointNames(core.NewSavepointNames(t.savepointPrefix))
+	}
+
+	var txCompleter core.Completer = completer{tx: currentTX}
+	if restarter, ok := currentTX.(core.Restarter); ok {
+		txCompleter = restartingCompleter{completer: completer{tx: currentTX}, Restarter: restarter}
+	}
+
+	// The session settings are applied once the transaction is started, after the savepoint of the retry protocol
+	if outermost && t.sessionSettings != nil {
+		txCompleter = core.StartWith(txCompleter, func(ctx context.Context) error {
+			return t.applySessionSettings(ctx, tx)
+		})
 	}
 
-	newDB, currentTX := t.nestedTransactionsStrategy(currentDB, tx)
-	defer func() {
-		_ = currentTX.Rollback() // If rollback fails, there's nothing to do, the transaction will expire by itself
-	}()
-	txCtx := txToContext(ctx, newDB)
+	return newDB, txCompleter
+}
 
-	if err := txFunc(txCtx); err != nil {
-		return err
+// restartingCompleter is a completer of an outermost transaction that the core restarts, see [core.Restarter].
+type restartingCompleter struct {
+	completer
+	core.Restarter
+}
+
+// begin begins a transaction with the DB handler, applying the read-only, MySQL, SQLite, SQL Server, Oracle and Firebird options to the outermost ones.
+func (t *Transactor) begin(ctx context.Context, db sqlxDB) (*sqlx.Tx, error) {
+	if _, outermost := db.(*sqlx.DB); !outermost {
+		return db.BeginTxx(ctx, nil) //nolint:wrapcheck
 	}
 
-	if err := currentTX.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if t.mysql != nil {
+		return t.mysql.begin(ctx, db, core.IsReadOnly(ctx))
 	}
 
-	return nil
+	if t.sqlite != nil {
+		return t.sqlite.begin(ctx, db, core.IsReadOnly(ctx))
+	}
+
+	if t.oracle != nil {
+		if core.IsReadOnly(ctx) {
+			return beginOracle(ctx, db, t.oracle.AsReadOnly())
+		}
+
+		return beginOracle(ctx, db, *t.oracle)
+	}
+
+	if t.firebird != nil {
+		if core.IsReadOnly(ctx) {
+			return beginFirebird(ctx, db, t.firebird.AsReadOnly())
+		}
+
+		return beginFirebird(ctx, db, *t.firebird)
+	}
+
+	opts := &sql.TxOptions{ReadOnly: core.IsReadOnly(ctx)}
+	if t.mssql != nil && t.mssql.Snapshot {
+		opts.Isolation = sql.LevelSnapshot
+	}
+
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if t.mssql != nil {
+		if err := t.mssql.setXactAbort(ctx, tx); err != nil {
+			return nil, errors.Join(err, tx.Rollback())
+		}
+	}
+
+	return tx, nil
+}
+
+// completer adapts a transaction to the core, which completes the transactions with a context.
+type completer struct {
+	tx sqlxTx
+}
+
+func (c completer) Commit(context.Context) error {
+	return c.tx.Commit() //nolint:wrapcheck
+}
+
+func (c completer) Rollback(context.Context) error {
+	return c.tx.Rollback() //nolint:wrapcheck
+}
+
+// Shutdown gracefully shuts down the transactor.
+// New outermost transactions are refused with [ErrShuttingDown], while the running ones can still use nested transactions.
+// Shutdown waits for the running transactions to finish. If the context expires first, the remaining transactions are
+// aborted by canceling their context, and Shutdown returns how many were aborted along with the context error, without
+// waiting for their rollback.
+// It's meant to be called before closing the [sqlx.DB].
+func (t *Transactor) Shutdown(ctx context.Context) (int, error) {
+	return t.core.Shutdown(ctx) //nolint:wrapcheck
 }
 
 func IsWithinTransaction(ctx context.Context) bool {
-	return ctx.Value(transactorKey{}) != nil
+	return core.IsWithinTransaction[sqlxDB](ctx)
+}
+
+// WithoutTransaction returns a copy of the context without the current transaction, if any.
+// The DB handler returned by the [DBGetter] for this context is the original DB, and [IsWithinTransaction] reports false.
+// It's meant for work that must not be part of the current transaction: the state of the transaction, such as its lock
+// or its read-only flag, is removed as well. This context is still canceled along with ctx, when the transaction is
+// done or aborted by Shutdown, so use [DetachedContext] for work that outlives the transaction.
+func WithoutTransaction(ctx context.Context) context.Context {
+	// The fake transactions are left as well, so that the TxGetter fails as with the real transactor
+	if isWithinFakeTransaction(ctx) {
+		ctx = context.WithValue(ctx, fakeTransactionKey{}, nil)
+	}
+
+	return core.WithoutTransaction[sqlxDB](ctx)
+}
+
+// DetachedContext returns a copy of the context without the current transaction, and that is not canceled when ctx is canceled.
+// It's meant for background work spawned from within a transaction, such as:
+//
+//	go doSomething(DetachedContext(ctx))
+func DetachedContext(ctx context.Context) context.Context {
+	return WithoutTransaction(context.WithoutCancel(ctx))
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/Thiht/transactor/internal/core"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -64,21 +65,11 @@ var (
 	_ sqlxTx = &sqlx.Tx{}
 )
 
-type (
-	transactorKey struct{}
-	// DBGetter is used to get the current DB handler from the context.
-	// It returns the current transaction if there is one, otherwise it will return the original DB.
-	DBGetter func(context.Context) DB
-)
-
-func txToContext(ctx context.Context, tx sqlxDB) context.Context {
-	return context.WithValue(ctx, transactorKey{}, tx)
-}
+// DBGetter is used to get the current DB handler from the context.
+// It returns the current transaction if there is one, otherwise it will return the original DB.
+type DBGetter func(context.Context) DB
 
 func txFromContext(ctx context.Context) sqlxDB {
-	if tx, ok := ctx.Value(transactorKey{}).(sqlxDB); ok {
-		return tx
-	}
-
-	return nil
+	tx, _ := core.TxFromContext[sqlxDB](ctx)
+	return tx
 }
@@ -0,0 +1,48 @@
+package stdlib
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+type (
+	// CircuitState is the state of a circuit breaker.
+	CircuitState = core.CircuitState
+
+	// CircuitOpenError is returned when starting a transaction while the circuit breaker is open.
+	CircuitOpenError = core.CircuitOpenError
+
+	// CircuitBreaker configures the circuit breaker around the outermost transactions.
+	CircuitBreaker = core.CircuitBreaker
+)
+
+const (
+	// CircuitClosed lets all the transactions through.
+	CircuitClosed = core.CircuitClosed
+	// CircuitOpen makes the transactions fail fast with a [*CircuitOpenError].
+	CircuitOpen = core.CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe transactions through to check whether the database recovered.
+	CircuitHalfOpen = core.CircuitHalfOpen
+)
+
+// WithCircuitBreaker makes the outermost transactions fail fast with a [*CircuitOpenError] after consecutive failures,
+// instead of waiting for an unavailable database. After [CircuitBreaker.OpenDuration], probe transactions are let through,
+// and the circuit breaker closes again as soon as one of them succeeds.
+func WithCircuitBreaker(config CircuitBreaker) Option {
+	return func(t *Transactor) {
+		t.core.SetCircuitBreaker(config, isConnectionError)
+	}
+}
+
+// CircuitState returns the current state of the circuit breaker.
+// It returns [CircuitClosed] if the circuit breaker is disabled.
+func (t *Transactor) CircuitState() CircuitState {
+	return t.core.CircuitState()
+}
+
+func isConnectionError(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone)
+}
@@ -0,0 +1,103 @@
+package stdlib
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+// WithConcurrencySafeTransactions makes the DB handler returned by the [DBGetter] within a transaction safe for concurrent use,
+// for example by goroutines of an errgroup started in the callback of WithinTransaction.
+// The statements are serialized on the transaction, and the results of the queries are read entirely before being returned
+// so that the connection is free for the next statement: they're held in memory, so large results should be avoided. The [sql.Stmt] returned by Prepare are not serialized.
+// Nested transactions must not be started concurrently.
+func WithConcurrencySafeTransactions() Option {
+	return func(t *Transactor) {
+		t.concurrencySafe = true
+		t.core.SetConcurrencySafe()
+	}
+}
+
+// concurrencySafeDB returns a concurrency safe DB handler for the transaction, if enabled.
+func (t *Transactor) concurrencySafeDB(ctx context.Context, tx DB) DB {
+	if !t.concurrencySafe {
+		return tx
+	}
+
+	lock := core.TxLockFromContext(ctx)
+	if lock == nil {
+		return tx
+	}
+
+	return &concurrencySafeTx{tx: tx, lock: lock, buffer: t.buffer}
+}
+
+// concurrencySafeTx is a DB handler serializing the statements executed on a shared transaction.
+type concurrencySafeTx struct {
+	tx     DB
+	lock   *sync.Mutex
+	buffer *sql.DB
+}
+
+func (db *concurrencySafeTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.tx.ExecContext(ctx, query, args...) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.tx.PrepareContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.buffer.QueryContext(core.BufferedRowsToContext(ctx, rows), "") //nolint:wrapcheck
+}
+
+func (db *concurrencySafeTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	rows, err := db.query(ctx, query, args...)
+	if err != nil {
+		rows = core.FailedRows(err)
+	}
+
+	return db.buffer.QueryRowContext(core.BufferedRowsToContext(ctx, rows), "")
+}
+
+func (db *concurrencySafeTx) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *concurrencySafeTx) Prepare(query string) (*sql.Stmt, error) {
+	return db.PrepareContext(context.Background(), query)
+}
+
+func (db *concurrencySafeTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *concurrencySafeTx) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// query executes a query and reads its results entirely.
+func (db *concurrencySafeTx) query(ctx context.Context, query string, args ...any) (*core.BufferedRows, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	rows, err := db.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return core.ReadRows(rows)
+}
@@ -20,5 +20,12 @@ func NewFakeTransactor(db *sql.DB) (transactor.Transactor, DBGetter) {
 type fakeTransactor struct{}
 
 func (fakeTransactor) WithinTransaction(ctx context.Context, txFunc func(context.Context) error) error {
-	return txFunc(ctx)
+	return txFunc(context.WithValue(ctx, fakeTransactionKey{}, true))
+}
+
+// fakeTransactionKey marks the contexts within a fake transaction, so that the [TxGetter] accepts them.
+type fakeTransactionKey struct{}
+
+func isWithinFakeTransaction(ctx context.Context) bool {
+	return ctx.Value(fakeTransactionKey{}) != nil
 }
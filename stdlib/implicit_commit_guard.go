@@ -0,0 +1,102 @@
+package stdlib
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+// ErrImplicitCommit is returned by the DB handler returned by the [DBGetter] within a transaction,
+// when a statement would implicitly commit the transaction, see [WithImplicitCommitGuard].
+var ErrImplicitCommit = core.ErrImplicitCommit
+
+// ImplicitCommits lists the statements that implicitly commit the current transaction on a database, see [WithImplicitCommitGuard].
+type ImplicitCommits = core.ImplicitCommits
+
+// MySQLImplicitCommits returns the statements implicitly committing the transaction on MySQL and MariaDB:
+// the DDL statements except the temporary tables, LOCK TABLES, and the administration statements.
+// The content of the executable comments, such as /*!50100 CREATE TABLE ... */, is checked as well.
+func MySQLImplicitCommits() *ImplicitCommits {
+	return core.MySQLImplicitCommits()
+}
+
+// OracleImplicitCommits returns the statements implicitly committing the transaction on Oracle: the DDL statements.
+func OracleImplicitCommits() *ImplicitCommits {
+	return core.OracleImplicitCommits()
+}
+
+// WithImplicitCommitGuard rejects the statements that implicitly commit the current transaction, such as the DDL
+// statements on MySQL and Oracle, when they're executed by the DB handler returned by the [DBGetter] within a
+// transaction. They fail with [ErrImplicitCommit] without being executed, instead of silently committing the changes
+// made so far and running the rest of the transaction outside of any transaction.
+// The statements are recognized by their leading keywords, after the comments.
+func WithImplicitCommitGuard(statements *ImplicitCommits) Option {
+	return func(t *Transactor) {
+		t.implicitCommits = statements
+	}
+}
+
+// implicitCommitGuardDB returns a DB handler rejecting the statements implicitly committing the transaction, if enabled.
+func (t *Transactor) implicitCommitGuardDB(tx DB) DB {
+	if t.implicitCommits == nil {
+		return tx
+	}
+
+	return &implicitCommitGuardTx{DB: tx, statements: t.implicitCommits, buffer: t.buffer}
+}
+
+// implicitCommitGuardTx is a DB handler rejecting the statements implicitly committing the transaction.
+type implicitCommitGuardTx struct {
+	DB
+	statements *core.ImplicitCommits
+	buffer     *sql.DB
+}
+
+func (db *implicitCommitGuardTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := db.statements.Check(query); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.DB.ExecContext(ctx, query, args...) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if err := db.statements.Check(query); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.DB.PrepareContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := db.statements.Check(query); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return db.DB.QueryContext(ctx, query, args...) //nolint:wrapcheck
+}
+
+func (db *implicitCommitGuardTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if err := db.statements.Check(query); err != nil {
+		return db.buffer.QueryRowContext(core.BufferedRowsToContext(ctx, core.FailedRows(err)), "")
+	}
+
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (db *implicitCommitGuardTx) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *implicitCommitGuardTx) Prepare(query string) (*sql.Stmt, error) {
+	return db.PrepareContext(context.Background(), query)
+}
+
+func (db *implicitCommitGuardTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *implicitCommitGuardTx) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
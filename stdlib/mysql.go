@@ -0,0 +1,61 @@
+package stdlib
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// MySQL configures the transactions of a MySQL or MariaDB database, see [WithMySQL].
+type MySQL struct {
+	// ConsistentSnapshot begins the outermost transactions with START TRANSACTION WITH CONSISTENT SNAPSHOT:
+	// with the REPEATABLE READ isolation level of InnoDB, their snapshot is taken when they begin
+	// instead of at their first read.
+	//
+	// database/sql can't begin a transaction with a custom statement: START TRANSACTION WITH CONSISTENT SNAPSHOT
+	// is executed as the first statement of the transaction begun by the driver, which it implicitly commits while
+	// it's still empty, and the new transaction replaces it on the same connection. The driver then commits or rolls
+	// back the new transaction as its own, which requires a driver that doesn't track the transaction state of the
+	// server, such as github.com/go-sql-driver/mysql, and no proxy between them multiplexing the transactions.
+	ConsistentSnapshot bool
+
+	// ReadOnly begins all the outermost transactions READ ONLY,
+	// not only the ones begun within a context returned by [WithReadOnly].
+	ReadOnly bool
+}
+
+// WithMySQL begins the outermost transactions with the given MySQL or MariaDB modes.
+// The transactions begun within a context returned by [WithReadOnly] are READ ONLY.
+// It's meant to be used with [NestedTransactionsSavepoints], and with [WithImplicitCommitGuard] and [MySQLImplicitCommits].
+func WithMySQL(config MySQL) Option {
+	return func(t *Transactor) {
+		t.mysql = &config
+	}
+}
+
+// begin begins a transaction with the modes of the configuration.
+func (m *MySQL) begin(ctx context.Context, db sqlDB, readOnly bool) (*sql.Tx, error) {
+	readOnly = readOnly || m.ReadOnly
+	if !m.ConsistentSnapshot {
+		return db.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly}) //nolint:wrapcheck
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	start := "START TRANSACTION WITH CONSISTENT SNAPSHOT"
+	if readOnly {
+		start += ", READ ONLY"
+	}
+
+	// START TRANSACTION implicitly commits the transaction begun by the driver, which is still empty,
+	// and replaces it on the same connection: the driver still commits or rolls back the new one
+	if _, err := tx.ExecContext(ctx, start); err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to %s: %w", start, err), tx.Rollback())
+	}
+
+	return tx, nil
+}
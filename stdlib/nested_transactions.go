@@ -0,0 +1,112 @@
+package stdlib
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+type (
+	// NestedTransactionsStrategy implements the nested transactions of a database, to use it with [NestedTransactionsWith].
+	// Its hooks are called with the outermost transaction, the depth of the nested transaction, and a savepoint name
+	// unique within the outermost transaction:
+	//   - BeginNested begins the nested transaction, typically by creating its savepoint,
+	//   - CommitNested commits it, typically by releasing its savepoint,
+	//   - RollbackNested rolls back its changes, typically by rolling back to its savepoint, and must keep the outermost
+	//     transaction usable.
+	//
+	// If the database limits the length of the savepoint names, the strategy can declare it by implementing
+	// MaxSavepointNameLength() int, so that the nested transactions fail with [ErrInvalidSavepointName] beyond it.
+	// The package strategytest provides a conformance test kit for the strategies.
+	NestedTransactionsStrategy = core.Strategy[*sql.Tx]
+
+	// NestedTx is a nested transaction, passed to the hooks of a [NestedTransactionsStrategy].
+	NestedTx = core.NestedTx[*sql.Tx]
+)
+
+// SavepointsStrategy returns the strategy of [NestedTransactionsSavepoints].
+func SavepointsStrategy() NestedTransactionsStrategy {
+	return core.SavepointsStrategy[*sql.Tx]{Dialect: core.SavepointsDialect(), Exec: execStatement}
+}
+
+// OracleStrategy returns the strategy of [NestedTransactionsOracle].
+func OracleStrategy() NestedTransactionsStrategy {
+	return core.SavepointsStrategy[*sql.Tx]{Dialect: core.OracleDialect(), Exec: execStatement}
+}
+
+// MSSQLStrategy returns the savepoints strategy of [NestedTransactionsMSSQL], without its XACT_STATE() checks.
+func MSSQLStrategy() NestedTransactionsStrategy {
+	return core.SavepointsStrategy[*sql.Tx]{Dialect: core.MSSQLDialect(), Exec: execStatement}
+}
+
+// FirebirdStrategy returns the strategy of [NestedTransactionsFirebird].
+func FirebirdStrategy() NestedTransactionsStrategy {
+	return core.SavepointsStrategy[*sql.Tx]{Dialect: core.FirebirdDialect(), Exec: execStatement}
+}
+
+// DB2Strategy returns the strategy of [NestedTransactionsDB2].
+func DB2Strategy() NestedTransactionsStrategy {
+	return core.SavepointsStrategy[*sql.Tx]{Dialect: core.DB2Dialect(), Exec: execStatement}
+}
+
+func execStatement(ctx context.Context, tx *sql.Tx, query string) error {
+	_, err := tx.ExecContext(ctx, query)
+	return err //nolint:wrapcheck
+}
+
+// NestedTransactionsWith is a nested transactions implementation using the given strategy.
+// It can be used to support a database the package doesn't ship a strategy for, or to instrument one of the strategies:
+//
+//	transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsWith(myStrategy{stdlib.SavepointsStrategy()}))
+func NestedTransactionsWith(strategy NestedTransactionsStrategy) nestedTransactionsFunc {
+	return func(db sqlDB, tx *sql.Tx) (sqlDB, sqlTx) {
+		switch typedDB := db.(type) {
+		case *sql.DB:
+			return &nestedTransaction{Tx: tx, NestedTransaction: core.NewNestedTransaction(strategy, tx, sql.ErrTxDone)}, tx
+
+		case *nestedTransaction:
+			nestedTransaction := &nestedTransaction{Tx: tx, NestedTransaction: typedDB.Nest()}
+			return nestedTransaction, nestedTransaction
+
+		default:
+			panic("unsupported type")
+		}
+	}
+}
+
+type nestedTransaction struct {
+	*sql.Tx
+	*core.NestedTransaction[*sql.Tx]
+}
+
+func (t *nestedTransaction) BeginTx(ctx context.Context, _ *sql.TxOptions) (*sql.Tx, error) {
+	if err := t.NestedTransaction.Begin(ctx); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return t.Tx, nil
+}
+
+func (t *nestedTransaction) Commit() error {
+	return t.NestedTransaction.Commit(context.Background()) //nolint:wrapcheck
+}
+
+func (t *nestedTransaction) Rollback() error {
+	return t.NestedTransaction.Rollback(context.Background()) //nolint:wrapcheck
+}
+
+// rollbackOnlyTx is an outermost transaction that is rolled back instead of committed once its strategy made it rollback-only.
+type rollbackOnlyTx struct {
+	*sql.Tx
+	checker core.RollbackOnlyChecker
+}
+
+func (t rollbackOnlyTx) Commit() error {
+	if err := t.checker.RollbackOnly(); err != nil {
+		return errors.Join(err, t.Tx.Rollback())
+	}
+
+	return t.Tx.Commit() //nolint:wrapcheck
+}
@@ -0,0 +1,164 @@
+package stdlib
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrUnknownDatabase is returned by [NestedTransactionsAuto] when the database can't be detected.
+var ErrUnknownDatabase = errors.New("unable to detect the database to choose a nested transactions strategy")
+
+// NestedTransactionsAuto detects the database and returns the matching nested transactions strategy:
+// [NestedTransactionsSavepoints] for PostgreSQL, MySQL, MariaDB and SQLite, [NestedTransactionsCockroachDB] for CockroachDB,
+// whose outermost transactions are then run again when they fail with a retryable error, [NestedTransactionsOracle] for Oracle,
+// [NestedTransactionsMSSQL] for Microsoft SQL Server, [NestedTransactionsDB2] for IBM Db2,
+// [NestedTransactionsFirebird] for Firebird, and [NestedTransactionsDuckDB] for DuckDB.
+// The database is detected from the type of the driver, or from its version if the driver is unknown or shared by several
+// databases, like the PostgreSQL drivers used by CockroachDB.
+// It returns [ErrUnknownDatabase] if the database can't be detected, so that a misconfiguration fails at startup
+// rather than at the first nested transaction.
+func NestedTransactionsAuto(ctx context.Context, db *sql.DB) (nestedTransactionsFunc, error) {
+	strategy, probes := strategyFromDriver(db.Driver())
+	if strategy != nil {
+		return strategy, nil
+	}
+
+	return strategyFromVersion(ctx, db, probes)
+}
+
+// strategyFromDriver detects the database from the package of its driver.
+// It returns either the strategy, or the version probes to run when the driver can't tell the database.
+func strategyFromDriver(d driver.Driver) (nestedTransactionsFunc, []versionProbe) {
+	typ := reflect.TypeOf(d)
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch pkg := typ.PkgPath(); {
+	case strings.HasPrefix(pkg, "github.com/jackc/pgx"),
+		strings.HasPrefix(pkg, "github.com/lib/pq"):
+		return nil, []versionProbe{postgresVersionProbe()}
+
+	case strings.HasPrefix(pkg, "github.com/go-sql-driver/mysql"),
+		strings.HasPrefix(pkg, "modernc.org/sqlite"),
+		strings.HasPrefix(pkg, "github.com/mattn/go-sqlite3"):
+		return NestedTransactionsSavepoints, nil
+
+	case strings.HasPrefix(pkg, "github.com/sijms/go-ora"),
+		strings.HasPrefix(pkg, "github.com/godror/godror"):
+		return NestedTransactionsOracle, nil
+
+	case strings.HasPrefix(pkg, "github.com/microsoft/go-mssqldb"),
+		strings.HasPrefix(pkg, "github.com/denisenkom/go-mssqldb"):
+		return NestedTransactionsMSSQL, nil
+
+	case strings.HasPrefix(pkg, "github.com/ibmdb/go_ibm_db"):
+		return NestedTransactionsDB2, nil
+
+	case strings.HasPrefix(pkg, "github.com/nakagami/firebirdsql"):
+		return NestedTransactionsFirebird, nil
+
+	case strings.HasPrefix(pkg, "github.com/marcboeker/go-duckdb"),
+		strings.HasPrefix(pkg, "github.com/duckdb/duckdb-go"):
+		return NestedTransactionsDuckDB, nil
+
+	default:
+		return nil, versionProbes()
+	}
+}
+
+// versionProbe detects the database from the version returned by its query, the strategy is nil if it's not a match.
+type versionProbe struct {
+	query    string
+	strategy func(version string) nestedTransactionsFunc
+}
+
+// postgresVersionProbe tells CockroachDB apart from PostgreSQL, since they share the same drivers.
+func postgresVersionProbe() versionProbe {
+	return versionProbe{
+		query: "SELECT version()",
+		strategy: func(version string) nestedTransactionsFunc {
+			switch {
+			case strings.Contains(version, "CockroachDB"):
+				return NestedTransactionsCockroachDB
+			case strings.Contains(version, "PostgreSQL"):
+				return NestedTransactionsSavepoints
+			default:
+				return nil
+			}
+		},
+	}
+}
+
+// versionProbes returns the probes detecting any database, each with the first query it supports.
+func versionProbes() []versionProbe {
+	return []versionProbe{
+		{
+			query: "SELECT sqlite_version()",
+			strategy: func(string) nestedTransactionsFunc {
+				return NestedTransactionsSavepoints
+			},
+		},
+		{
+			// Supported by SQL Server, MySQL and MariaDB
+			query: "SELECT @@VERSION",
+			strategy: func(version string) nestedTransactionsFunc {
+				if strings.Contains(version, "Microsoft SQL Server") {
+					return NestedTransactionsMSSQL
+				}
+
+				return NestedTransactionsSavepoints
+			},
+		},
+		postgresVersionProbe(),
+		{
+			query: "SELECT banner FROM v$version",
+			strategy: func(string) nestedTransactionsFunc {
+				return NestedTransactionsOracle
+			},
+		},
+		{
+			query: "SELECT service_level FROM sysibmadm.env_inst_info",
+			strategy: func(string) nestedTransactionsFunc {
+				return NestedTransactionsDB2
+			},
+		},
+		{
+			query: "SELECT rdb$get_context('SYSTEM', 'ENGINE_VERSION') FROM rdb$database",
+			strategy: func(string) nestedTransactionsFunc {
+				return NestedTransactionsFirebird
+			},
+		},
+		{
+			query: "SELECT library_version FROM pragma_version()",
+			strategy: func(string) nestedTransactionsFunc {
+				return NestedTransactionsDuckDB
+			},
+		},
+	}
+}
+
+// strategyFromVersion detects the database by querying its version with the probes.
+func strategyFromVersion(ctx context.Context, db DB, probes []versionProbe) (nestedTransactionsFunc, error) {
+	for _, probe := range probes {
+		var version string
+		if err := db.QueryRowContext(ctx, probe.query).Scan(&version); err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to detect the database: %w", ctx.Err())
+			}
+
+			continue
+		}
+
+		if strategy := probe.strategy(version); strategy != nil {
+			return strategy, nil
+		}
+	}
+
+	return nil, ErrUnknownDatabase
+}
@@ -0,0 +1,8 @@
+package stdlib
+
+import "database/sql"
+
+// NestedTransactionsDB2 is a nested transactions implementation using IBM Db2 savepoints.
+func NestedTransactionsDB2(db sqlDB, tx *sql.Tx) (sqlDB, sqlTx) {
+	return NestedTransactionsWith(DB2Strategy())(db, tx)
+}
@@ -0,0 +1,29 @@
+package stdlib
+
+import (
+	"database/sql"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+// ErrRollbackOnly is returned by WithinTransaction when committing an outermost transaction after one of its nested
+// transactions was rolled back, with [NestedTransactionsDuckDB] or [SubtransactionsFlatten].
+// The outermost transaction is rolled back instead.
+var ErrRollbackOnly = core.ErrRollbackOnly
+
+// NestedTransactionsDuckDB is a nested transactions implementation for DuckDB, which doesn't support savepoints.
+// The nested transactions are flattened into the outermost transaction: their changes can't be rolled back separately,
+// so rolling back a nested transaction marks the outermost transaction as rollback-only, and committing it then
+// rolls it back and fails with [ErrRollbackOnly].
+// Without savepoints, [Transactor.WithinSavepoint], [Transactor.TrySavepoint] and the statements of
+// [WithStatementSavepoints] fail with [ErrSavepointsNotSupported].
+func NestedTransactionsDuckDB(db sqlDB, tx *sql.Tx) (sqlDB, sqlTx) {
+	if _, outermost := db.(*sql.DB); outermost {
+		strategy := &core.RollbackOnlyStrategy[*sql.Tx]{}
+		newDB, _ := NestedTransactionsWith(strategy)(db, tx)
+		return newDB, rollbackOnlyTx{Tx: tx, checker: strategy}
+	}
+
+	// The nested transactions reuse the strategy of their outermost transaction
+	return NestedTransactionsWith(nil)(db, tx)
+}
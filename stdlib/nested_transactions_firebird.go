@@ -0,0 +1,9 @@
+package stdlib
+
+import "database/sql"
+
+// NestedTransactionsFirebird is a nested transactions implementation using Firebird savepoints.
+// The transaction parameters can be set with [WithFirebird].
+func NestedTransactionsFirebird(db sqlDB, tx *sql.Tx) (sqlDB, sqlTx) {
+	return NestedTransactionsWith(FirebirdStrategy())(db, tx)
+}
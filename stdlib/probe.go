@@ -0,0 +1,64 @@
+package stdlib
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+type (
+	// ProbeReport is the report of the capabilities of a database with a nested transactions strategy, returned by [Probe].
+	ProbeReport = core.ProbeReport
+
+	// ProbeCheck is the outcome of a capability check.
+	ProbeCheck = core.ProbeCheck
+)
+
+// Probe checks that the nested transactions strategy works with the database, for example at startup or in CI.
+// It checks the creation, release and rollback of a savepoint with the strategy, and the optional checks of the read-only
+// transactions and of the isolation levels accepted by the driver. Every check runs in a transaction that is rolled back.
+// It only returns an error if a transaction can't be started at all, for example if the database is unreachable.
+func Probe(ctx context.Context, db *sql.DB, strategy nestedTransactionsFunc) (ProbeReport, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ProbeReport{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	_ = tx.Rollback()
+
+	var report ProbeReport
+	report.Checks = append(report.Checks, probeSavepoints(ctx, db, strategy)...)
+	report.Checks = append(report.Checks, core.ProbeTxOptions(ctx, db)...)
+
+	return report, nil
+}
+
+// probeSavepoints checks the creation, release and rollback of a savepoint with the nested transactions strategy.
+func probeSavepoints(ctx context.Context, db *sql.DB, strategy nestedTransactionsFunc) []ProbeCheck {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		err = fmt.Errorf("failed to begin transaction: %w", err)
+		return core.ProbeSavepoints(ctx, func(context.Context) (core.Completer, error) {
+			return nil, err
+		})
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rootDB, _ := strategy(db, tx)
+	if named, ok := rootDB.(namedSavepoints); ok {
+		named.SetSavepointNames(core.NewSavepointNames(DefaultSavepointPrefix))
+	}
+
+	return core.ProbeSavepoints(ctx, func(ctx context.Context) (core.Completer, error) {
+		nestedTx, err := rootDB.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+
+		_, nestedTX := strategy(rootDB, nestedTx)
+		return completer{tx: nestedTX}, nil
+	})
+}
@@ -0,0 +1,40 @@
+package stdlib
+
+import (
+	"context"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+// DefaultSavepointPrefix is the prefix of the savepoint names of the nested transactions.
+const DefaultSavepointPrefix = core.DefaultSavepointPrefix
+
+// WithSavepointPrefix sets the prefix of the savepoint names of the nested transactions, [DefaultSavepointPrefix] by default.
+// The savepoints are named <prefix><id>_<depth>, where id is unique to each outermost transaction, so that they don't collide
+// with the savepoints created by your own SQL, and can be told apart in the logs.
+// The prefix must start with a letter and only contain letters, digits and underscores, NewTransactor panics with
+// [ErrInvalidSavepointName] otherwise. The nested transactions fail with [ErrInvalidSavepointName] if their savepoint name
+// exceeds the identifier length declared by the nested transactions strategy (30 characters for Oracle, 32 for SQL Server,
+// 63 otherwise), see [NestedTransactionsStrategy].
+func WithSavepointPrefix(prefix string) Option {
+	return func(t *Transactor) {
+		t.savepointPrefix = prefix
+	}
+}
+
+// CurrentSavepoint returns the name of the savepoint of the current nested transaction.
+// It returns false outside of a nested transaction, or if the nested transactions strategy doesn't use savepoints.
+func CurrentSavepoint(ctx context.Context) (string, bool) {
+	tx, ok := txFromContext(ctx).(namedSavepoints)
+	if !ok {
+		return "", false
+	}
+
+	return tx.CurrentSavepoint()
+}
+
+// namedSavepoints is implemented by the nested transactions strategies naming their savepoints.
+type namedSavepoints interface {
+	SetSavepointNames(names core.SavepointNames)
+	CurrentSavepoint() (string, bool)
+}
@@ -0,0 +1,91 @@
+package stdlib
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+// ErrWriterLockHeld is returned when an outermost read-write transaction is begun with [WithSQLite] from a context
+// descending from the one of the running read-write transaction, such as the one returned by [DetachedContext]:
+// waiting for the running transaction would deadlock if it waits for the new one.
+var ErrWriterLockHeld = core.ErrWriterLockHeld
+
+// SQLite configures the transactions of a SQLite database, see [WithSQLite].
+type SQLite struct {
+	// Exclusive begins the read-write transactions with BEGIN EXCLUSIVE instead of BEGIN IMMEDIATE,
+	// preventing the other connections from reading the database, unless it's in WAL mode.
+	Exclusive bool
+
+	// Readers is the pool of connections the read-only transactions begin on, see [WithReadOnly].
+	// It's typically a second [sql.DB] of the same database file, in WAL mode so that the readers don't block the writer.
+	// If nil, the read-only transactions begin on the DB of the transactor.
+	Readers *sql.DB
+}
+
+// WithSQLite adapts the transactions to the single writer of SQLite.
+//
+// By default, SQLite begins the transactions with a deferred BEGIN, which takes the write lock on the first write:
+// concurrent transactions that read before writing fail with SQLITE_BUSY in the middle of the transaction.
+// With WithSQLite, the outermost read-write transactions begin with BEGIN IMMEDIATE, which takes the write lock
+// when the transaction begins, and they are serialized by an in-process lock so that they wait for each other instead
+// of failing. An outermost read-write transaction begun while the transaction of its context holds the lock fails with
+// [ErrWriterLockHeld] instead of waiting for it.
+// The transactions begun within a context returned by [WithReadOnly] don't take any lock, and begin on the
+// [SQLite.Readers] pool if set.
+//
+// Nested transactions are unaffected, it's meant to be used with [NestedTransactionsSavepoints].
+func WithSQLite(config SQLite) Option {
+	return func(t *Transactor) {
+		t.sqlite = &config
+		t.core.SetWriterLock()
+	}
+}
+
+// WithReadOnly returns a context in which the outermost transactions are begun read-only.
+// With [WithSQLite], they begin on the reader pool and don't wait for the running read-write transaction.
+// The nested transactions of a read-only transaction are read-only as well.
+func WithReadOnly(ctx context.Context) context.Context {
+	return core.WithReadOnly(ctx)
+}
+
+// begin begins a read-only transaction on the readers, or a read-write one with BEGIN IMMEDIATE or BEGIN EXCLUSIVE.
+//
+// database/sql can't begin a transaction with a custom statement: the driver begins a deferred transaction, which
+// takes no lock until its first statement, and BEGIN IMMEDIATE replaces it on the same connection. The driver then
+// commits or rolls back the new transaction as its own, as the modernc.org/sqlite and github.com/mattn/go-sqlite3
+// drivers do.
+func (s *SQLite) begin(ctx context.Context, db sqlDB, readOnly bool) (*sql.Tx, error) {
+	if readOnly {
+		if s.Readers != nil {
+			db = s.Readers
+		}
+
+		return db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true}) //nolint:wrapcheck
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if _, err := tx.ExecContext(ctx, "ROLLBACK"); err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to replace the deferred transaction: %w", err), tx.Rollback())
+	}
+
+	begin := "BEGIN IMMEDIATE"
+	if s.Exclusive {
+		begin = "BEGIN EXCLUSIVE"
+	}
+
+	if _, err := tx.ExecContext(ctx, begin); err != nil {
+		// The connection is outside of any transaction: rolling back only releases it, and fails
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("failed to %s: %w", begin, err)
+	}
+
+	return tx, nil
+}
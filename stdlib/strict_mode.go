@@ -0,0 +1,112 @@
+package stdlib
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+type (
+	// MisuseKind is a kind of transaction misuse detected by the strict mode.
+	MisuseKind = core.MisuseKind
+
+	// MisuseError describes a transaction misuse detected by the strict mode.
+	MisuseError = core.MisuseError
+
+	// StrictMode configures the strict mode.
+	StrictMode = core.StrictMode
+)
+
+const (
+	// MisuseUseAfterReturn is the use of a transaction after its WithinTransaction returned.
+	MisuseUseAfterReturn = core.MisuseUseAfterReturn
+	// MisuseConcurrentUse is the use of a transaction by several goroutines at the same time.
+	MisuseConcurrentUse = core.MisuseConcurrentUse
+	// MisuseRowsOpenAtCommit is the commit of a transaction while rows it returned are still open.
+	MisuseRowsOpenAtCommit = core.MisuseRowsOpenAtCommit
+	// MisuseNestedFailureIgnored is the commit of a transaction although one of its nested transactions failed
+	// to create, release, or rollback to its savepoint, leaving the transaction in an unknown state.
+	MisuseNestedFailureIgnored = core.MisuseNestedFailureIgnored
+)
+
+// WithStrictMode enables a debug mode detecting the misuses of the transactions at runtime:
+// using a transaction after its WithinTransaction returned, using it from several goroutines concurrently,
+// leaving rows open at commit, and committing although a nested transaction failed on its savepoint.
+// The DB handlers returned by the [DBGetter] within a transaction are wrapped to watch their use.
+// It relies on stack traces, so it should only be used in development.
+func WithStrictMode(config StrictMode) Option {
+	return func(t *Transactor) {
+		t.core.SetStrictMode(config)
+	}
+}
+
+// strictDB returns a DB handler watching the use of the transaction of the context.
+// It returns the DB handler as is if the strict mode is disabled.
+func (t *Transactor) strictDB(ctx context.Context, db DB) DB {
+	tx := t.core.StrictTx(ctx)
+	if tx == nil {
+		return db
+	}
+
+	return &strictDB{DB: db, tx: tx}
+}
+
+// isOpen reports whether rows are still open, since Columns fails once they're closed.
+func isOpen(rows *sql.Rows) bool {
+	_, err := rows.Columns()
+	return err == nil
+}
+
+type strictDB struct {
+	DB
+	tx *core.StrictTx
+}
+
+func (db *strictDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer db.tx.Enter()()
+
+	return db.DB.ExecContext(ctx, query, args...) //nolint:wrapcheck
+}
+
+func (db *strictDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	defer db.tx.Enter()()
+
+	return db.DB.PrepareContext(ctx, query) //nolint:wrapcheck
+}
+
+func (db *strictDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	defer db.tx.Enter()()
+
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	db.tx.Track(func() bool {
+		return isOpen(rows)
+	})
+
+	return rows, nil
+}
+
+func (db *strictDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	defer db.tx.Enter()()
+
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (db *strictDB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *strictDB) Prepare(query string) (*sql.Stmt, error) {
+	return db.PrepareContext(context.Background(), query)
+}
+
+func (db *strictDB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *strictDB) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
@@ -0,0 +1,38 @@
+package stdlib
+
+import (
+	"context"
+
+	"github.com/Thiht/transactor/internal/core"
+)
+
+// ErrNoTransaction is returned by the [TxGetter] when the context is not within a transaction.
+var ErrNoTransaction = core.ErrNoTransaction
+
+type (
+	// TxGetter is used to get the current transaction from the context.
+	// It returns [ErrNoTransaction] if there is none, so that repository methods that are only correct within
+	// a transaction, such as SELECT ... FOR UPDATE, can declare it.
+	TxGetter func(context.Context) (DB, error)
+
+	// NoTxGetter is used to get the original DB handler, even from a context within a transaction.
+	NoTxGetter func(context.Context) DB
+)
+
+// TxGetter returns a [TxGetter] getting the transactions of the same transactor as the DBGetter.
+func (g DBGetter) TxGetter() TxGetter {
+	return func(ctx context.Context) (DB, error) {
+		if !IsWithinTransaction(ctx) && !isWithinFakeTransaction(ctx) {
+			return nil, ErrNoTransaction
+		}
+
+		return g(ctx), nil
+	}
+}
+
+// NoTxGetter returns a [NoTxGetter] getting the original DB handler of the same transactor as the DBGetter.
+func (g DBGetter) NoTxGetter() NoTxGetter {
+	return func(ctx context.Context) DB {
+		return g(WithoutTransaction(ctx))
+	}
+}
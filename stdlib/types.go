@@ -3,6 +3,8 @@ package stdlib
 import (
 	"context"
 	"database/sql"
+
+	"github.com/Thiht/transactor/internal/core"
 )
 
 // DB is the common interface between *[sql.DB] and *[sql.Tx].
@@ -35,21 +37,11 @@ var (
 	_ sqlTx = &sql.Tx{}
 )
 
-type (
-	transactorKey struct{}
-	// DBGetter is used to get the current DB handler from the context.
-	// It returns the current transaction if there is one, otherwise it will return the original DB.
-	DBGetter func(context.Context) DB
-)
-
-func txToContext(ctx context.Context, tx sqlDB) context.Context {
-	return context.WithValue(ctx, transactorKey{}, tx)
-}
+// DBGetter is used to get the current DB handler from the context.
+// It returns the current transaction if there is one, otherwise it will return the original DB.
+type DBGetter func(context.Context) DB
 
 func txFromContext(ctx context.Context) sqlDB {
-	if tx, ok := ctx.Value(transactorKey{}).(sqlDB); ok {
-		return tx
-	}
-
-	return nil
+	tx, _ := core.TxFromContext[sqlDB](ctx)
+	return tx
 }
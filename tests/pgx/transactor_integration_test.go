@@ -3,12 +3,15 @@ package pgx_test
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	pgxTransactor "github.com/Thiht/transactor/pgx"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/log"
@@ -56,6 +59,32 @@ func TestIntegrationTransactorPostgres(t *testing.T) {
 
 		transactor, dbGetter := pgxTransactor.NewTransactor(db)
 
+		t.Run("it should name the savepoints of the nested transactions with the prefix", func(t *testing.T) {
+			config, err := pgx.ParseConfig(dsn)
+			require.NoError(t, err)
+			recorder := &queryRecorder{}
+			config.Tracer = recorder
+
+			db, err := pgx.ConnectConfig(ctx, config)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, db.Close(ctx))
+			})
+
+			transactor, _ := pgxTransactor.NewTransactor(db, pgxTransactor.WithSavepointPrefix("app_"))
+
+			err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				return transactor.WithinTransaction(ctx, func(_ context.Context) error {
+					return nil
+				})
+			})
+			require.NoError(t, err)
+
+			require.Len(t, recorder.queries, 4)
+			require.Regexp(t, `^SAVEPOINT app_[a-z0-9]{8}_1$`, recorder.queries[1])
+			require.Regexp(t, `^RELEASE SAVEPOINT app_[a-z0-9]{8}_1$`, recorder.queries[2])
+		})
+
 		t.Run("it should rollback the transaction", func(t *testing.T) {
 			t.Cleanup(func() {
 				reset(ctx, db)
@@ -339,5 +368,427 @@ func TestIntegrationTransactorPostgres(t *testing.T) {
 				require.Equal(t, 110, amount)
 			})
 		})
+
+		t.Run("it should not use the transaction in a context without transaction", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(ctx, db)
+			})
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				ctx = pgxTransactor.WithoutTransaction(ctx)
+				require.False(t, pgxTransactor.IsWithinTransaction(ctx))
+
+				var amount int
+				err = dbGetter(ctx).QueryRow(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 100, amount)
+
+				return nil
+			})
+			require.NoError(t, err)
+		})
+
+		t.Run("it should abort the running transactions on shutdown", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(ctx, db)
+			})
+
+			transactor, dbGetter := pgxTransactor.NewTransactorFromPool(db)
+
+			started := make(chan struct{})
+			errs := make(chan error, 1)
+			go func() {
+				errs <- transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+					require.NoError(t, err)
+					close(started)
+
+					_, err = dbGetter(ctx).Exec(ctx, "SELECT pg_sleep(10)")
+					return err
+				})
+			}()
+
+			<-started
+			shutdownCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			t.Cleanup(cancel)
+
+			aborted, err := transactor.Shutdown(shutdownCtx)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			require.Equal(t, 1, aborted)
+			require.Error(t, <-errs)
+
+			var amount int
+			err = db.QueryRow(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 100, amount)
+		})
+
+		t.Run("it should detect a deadlock on the pool", func(t *testing.T) {
+			config, err := pgxpool.ParseConfig(dsn)
+			require.NoError(t, err)
+			config.MaxConns = 1
+
+			db, err := pgxpool.NewWithConfig(ctx, config)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+			})
+
+			transactor, dbGetter := pgxTransactor.NewTransactorFromPool(db, pgxTransactor.WithDeadlockDetection(pgxTransactor.DeadlockDetection{
+				Threshold: 100 * time.Millisecond,
+			}))
+
+			err = transactor.WithinTransaction(ctx, func(_ context.Context) error {
+				// The transaction context is not used, so the query waits for the connection held by the transaction
+				_, err := dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				return err
+			})
+			var deadlockErr *pgxTransactor.DeadlockError
+			require.ErrorAs(t, err, &deadlockErr)
+		})
+
+		t.Run("it should not report a slow query once it acquired its connection", func(t *testing.T) {
+			config, err := pgxpool.ParseConfig(dsn)
+			require.NoError(t, err)
+			config.MaxConns = 2
+
+			db, err := pgxpool.NewWithConfig(ctx, config)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+			})
+
+			transactor, dbGetter := pgxTransactor.NewTransactorFromPool(db, pgxTransactor.WithDeadlockDetection(pgxTransactor.DeadlockDetection{
+				Threshold: 100 * time.Millisecond,
+			}))
+
+			err = transactor.WithinTransaction(ctx, func(_ context.Context) error {
+				// The query exhausts the pool while it's running, but it already acquired its connection
+				_, err := dbGetter(ctx).Exec(ctx, "SELECT pg_sleep(0.3)")
+				return err
+			})
+			require.NoError(t, err)
+		})
+
+		t.Run("it should serialize the statements executed concurrently within a transaction", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(ctx, db)
+			})
+
+			transactor, dbGetter := pgxTransactor.NewTransactorFromPool(db, pgxTransactor.WithConcurrencySafeTransactions())
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				var wg sync.WaitGroup
+				for range 10 {
+					wg.Go(func() {
+						var amount int
+						err := dbGetter(ctx).QueryRow(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+						assert.NoError(t, err)
+
+						_, err = dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = amount + 1 WHERE id = 1")
+						assert.NoError(t, err)
+					})
+				}
+				wg.Wait()
+
+				return nil
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRow(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 110, amount)
+		})
+
+		t.Run("it should release the transaction once when a row is scanned twice", func(t *testing.T) {
+			transactor, dbGetter := pgxTransactor.NewTransactorFromPool(db, pgxTransactor.WithConcurrencySafeTransactions())
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				row := dbGetter(ctx).QueryRow(ctx, "SELECT amount FROM balances WHERE id = 1")
+
+				var amount int
+				require.NoError(t, row.Scan(&amount))
+				_ = row.Scan(&amount) // Unlocking the transaction again would abort the program
+
+				_, err := dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = amount WHERE id = 1")
+				return err
+			})
+			require.NoError(t, err)
+		})
+
+		t.Run("it should rollback to a savepoint and keep the transaction usable", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(ctx, db)
+			})
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				ok, err := transactor.TrySavepoint(ctx, "insert_balance", func(ctx context.Context) error {
+					_, err := dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+					require.NoError(t, err)
+
+					_, err = dbGetter(ctx).Exec(ctx, "INSERT INTO balances (id, amount) VALUES (1, 0)")
+					return err
+				}, func(err error) bool {
+					var pgErr *pgconn.PgError
+					return errors.As(err, &pgErr) && pgErr.Code == "23505" // unique_violation
+				})
+				require.NoError(t, err)
+				require.False(t, ok)
+
+				_, err = dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+				return err
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRow(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 110, amount)
+		})
+
+		t.Run("it should probe the capabilities of the database", func(t *testing.T) {
+			report, err := pgxTransactor.Probe(ctx, db)
+			require.NoError(t, err)
+			require.True(t, report.Supported(), report)
+		})
+
+		t.Run("it should rollback a failed statement to its savepoint and keep the transaction usable", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(ctx, db)
+			})
+
+			transactor, dbGetter := pgxTransactor.NewTransactorFromPool(db, pgxTransactor.WithStatementSavepoints())
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				_, err = dbGetter(ctx).Exec(ctx, "INSERT INTO balances (id, amount) VALUES (1, 0)")
+				var pgErr *pgconn.PgError
+				require.ErrorAs(t, err, &pgErr)
+				require.Equal(t, "23505", pgErr.Code) // unique_violation
+
+				rows, err := dbGetter(ctx).Query(ctx, "SELECT amount FROM balances")
+				require.NoError(t, err)
+				rows.Close()
+				require.NoError(t, rows.Err())
+
+				_, err = dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+				return err
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRow(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 60, amount)
+		})
+
+		t.Run("it should detect the misuses of the transactions in strict mode", func(t *testing.T) {
+			var misuses []pgxTransactor.MisuseKind
+			transactor, dbGetter := pgxTransactor.NewTransactorFromPool(db, pgxTransactor.WithStrictMode(pgxTransactor.StrictMode{
+				OnMisuse: func(err *pgxTransactor.MisuseError) {
+					misuses = append(misuses, err.Kind)
+				},
+			}))
+
+			var txCtx context.Context
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				txCtx = ctx
+
+				rows, err := dbGetter(ctx).Query(ctx, "SELECT amount FROM balances")
+				require.NoError(t, err)
+				t.Cleanup(rows.Close)
+
+				return nil
+			})
+			require.Error(t, err) // The connection is busy with the open rows
+			require.Equal(t, []pgxTransactor.MisuseKind{pgxTransactor.MisuseRowsOpenAtCommit}, misuses)
+
+			_, err = dbGetter(txCtx).Exec(txCtx, "UPDATE balances SET amount = 50 WHERE id = 1")
+			require.Error(t, err)
+			require.Equal(t, []pgxTransactor.MisuseKind{pgxTransactor.MisuseRowsOpenAtCommit, pgxTransactor.MisuseUseAfterReturn}, misuses)
+		})
+
+		t.Run("it should flatten the nested transactions exceeding the subtransaction budget", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(ctx, db)
+			})
+
+			var exceeded int
+			transactor, dbGetter := pgxTransactor.NewTransactorFromPool(db, pgxTransactor.WithSubtransactionBudget(pgxTransactor.SubtransactionBudget{
+				Overflow: pgxTransactor.SubtransactionsFlatten,
+				OnExceeded: func(context.Context, int) {
+					exceeded++
+				},
+			}))
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				for range 70 {
+					if err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+						_, err := dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = amount + 1 WHERE id = 1")
+						return err
+					}); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, 6, exceeded)
+
+			var amount int
+			err = dbGetter(ctx).QueryRow(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 170, amount)
+		})
+
+		t.Run("it should apply the session settings to the transactions", func(t *testing.T) {
+			transactor, dbGetter := pgxTransactor.NewTransactorFromPool(db, pgxTransactor.WithSessionSettings(func(context.Context) map[string]string {
+				return map[string]string{"app.tenant_id": "42"}
+			}))
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					var tenantID string
+					err := dbGetter(ctx).QueryRow(ctx, "SELECT current_setting('app.tenant_id')").Scan(&tenantID)
+					require.NoError(t, err)
+					require.Equal(t, "42", tenantID)
+
+					return nil
+				})
+			})
+			require.NoError(t, err)
+
+			// The settings are local to the transaction
+			var tenantID string
+			err = dbGetter(ctx).QueryRow(ctx, "SELECT coalesce(current_setting('app.tenant_id', true), '')").Scan(&tenantID)
+			require.NoError(t, err)
+			require.Empty(t, tenantID)
+		})
 	})
 }
+
+func TestIntegrationTransactorCockroachDB(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testcontainers.DefaultLoggingHook = func(log.Logger) testcontainers.ContainerLifecycleHooks {
+		return testcontainers.ContainerLifecycleHooks{}
+	}
+
+	cockroachContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "cockroachdb/cockroach:latest-v24.3",
+			Cmd:          []string{"start-single-node", "--insecure"},
+			ExposedPorts: []string{"26257/tcp"},
+			WaitingFor:   wait.ForLog("CockroachDB node starting").WithStartupTimeout(time.Minute),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, cockroachContainer.Terminate(ctx))
+	})
+
+	containerPort, err := cockroachContainer.MappedPort(ctx, "26257/tcp")
+	require.NoError(t, err)
+
+	dsn := "postgres://root@localhost:" + containerPort.Port() + "/defaultdb?sslmode=disable"
+
+	reset := func(ctx context.Context, db *pgxpool.Pool) {
+		t.Helper()
+		_, err := db.Exec(ctx, "UPDATE balances SET amount = 100 WHERE id = 1")
+		require.NoError(t, err)
+	}
+
+	t.Run("with a pgx pool", func(t *testing.T) {
+		db, err := pgxpool.New(ctx, dsn)
+		require.NoError(t, err)
+		t.Cleanup(db.Close)
+
+		// The BIGSERIAL ids of CockroachDB aren't sequential, so the init script of PostgreSQL can't be used
+		_, err = db.Exec(ctx, "CREATE TABLE balances (id INT PRIMARY KEY, amount INT NOT NULL); INSERT INTO balances VALUES (1, 100)")
+		require.NoError(t, err)
+
+		transactor, dbGetter := pgxTransactor.NewTransactorFromPool(db, pgxTransactor.WithCockroachDB())
+
+		t.Run("it should restart the transactions conflicting with each other", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(ctx, db)
+			})
+
+			var wg sync.WaitGroup
+			for range 10 {
+				wg.Go(func() {
+					err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+						var amount int
+						if err := dbGetter(ctx).QueryRow(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount); err != nil {
+							return err
+						}
+						time.Sleep(10 * time.Millisecond) // Lets the other transactions read before the write
+
+						_, err := dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = $1 WHERE id = 1", amount+1)
+						return err
+					})
+					assert.NoError(t, err)
+				})
+			}
+			wg.Wait()
+
+			var amount int
+			err := dbGetter(ctx).QueryRow(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 110, amount)
+		})
+
+		t.Run("it should rollback the nested transaction", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(ctx, db)
+			})
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).Exec(ctx, "UPDATE balances SET amount = 60 WHERE id = 1")
+					require.NoError(t, err)
+
+					return errors.New("an error occurred")
+				})
+				require.Error(t, err)
+
+				return nil
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRow(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 50, amount)
+		})
+	})
+}
+
+// queryRecorder is a tracer recording the SQL of the queries.
+type queryRecorder struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (r *queryRecorder) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queries = append(r.queries, data.SQL)
+	return ctx
+}
+
+func (r *queryRecorder) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
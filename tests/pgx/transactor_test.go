@@ -6,6 +6,7 @@ import (
 
 	pgxTransactor "github.com/Thiht/transactor/pgx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsWithinTransaction(t *testing.T) {
@@ -18,3 +19,122 @@ func TestIsWithinTransaction(t *testing.T) {
 		assert.False(t, pgxTransactor.IsWithinTransaction(ctx))
 	})
 }
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should refuse new transactions", func(t *testing.T) {
+		t.Parallel()
+
+		// The connection is never used since the transactor is shut down before any transaction
+		transactor, _ := pgxTransactor.NewTransactor(nil)
+
+		aborted, err := transactor.Shutdown(context.Background())
+		require.NoError(t, err)
+		require.Zero(t, aborted)
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			return nil
+		})
+		require.ErrorIs(t, err, pgxTransactor.ErrShuttingDown)
+	})
+}
+
+func TestAdmissionControl(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should panic if the maximum number of transactions isn't positive", func(t *testing.T) {
+		t.Parallel()
+
+		require.Panics(t, func() {
+			pgxTransactor.NewTransactor(nil, pgxTransactor.WithAdmissionControl(pgxTransactor.AdmissionControl{
+				MaxConcurrentTransactions: 0,
+			}))
+		})
+	})
+}
+
+func TestDeadlockDetection(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should panic if the threshold isn't positive", func(t *testing.T) {
+		t.Parallel()
+
+		require.Panics(t, func() {
+			pgxTransactor.NewTransactor(nil, pgxTransactor.WithDeadlockDetection(pgxTransactor.DeadlockDetection{
+				Threshold: 0,
+			}))
+		})
+	})
+}
+
+func TestSavepointNaming(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should panic if the prefix is not a valid identifier", func(t *testing.T) {
+		t.Parallel()
+
+		defer func() {
+			err, ok := recover().(error)
+			require.True(t, ok)
+			require.ErrorIs(t, err, pgxTransactor.ErrInvalidSavepointName)
+		}()
+
+		pgxTransactor.NewTransactor(nil, pgxTransactor.WithSavepointPrefix("1; DROP TABLE users; --"))
+	})
+}
+
+func TestWithoutTransaction(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should return false if the context is without transaction", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := pgxTransactor.WithoutTransaction(context.Background())
+		assert.False(t, pgxTransactor.IsWithinTransaction(ctx))
+	})
+}
+
+func TestTxGetter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should return an error if the context is not within a transaction", func(t *testing.T) {
+		t.Parallel()
+
+		_, dbGetter := pgxTransactor.NewTransactor(nil)
+
+		_, err := dbGetter.TxGetter()(context.Background())
+		require.ErrorIs(t, err, pgxTransactor.ErrNoTransaction)
+	})
+
+	t.Run("it should return the DB handler within a fake transaction", func(t *testing.T) {
+		t.Parallel()
+
+		transactor, dbGetter := pgxTransactor.NewFakeTransactor(nil)
+		txGetter := dbGetter.TxGetter()
+
+		err := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			tx, err := txGetter(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, dbGetter(ctx), tx)
+
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("it should return an error without the fake transaction", func(t *testing.T) {
+		t.Parallel()
+
+		transactor, dbGetter := pgxTransactor.NewFakeTransactor(nil)
+		txGetter := dbGetter.TxGetter()
+
+		err := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			_, err := txGetter(pgxTransactor.WithoutTransaction(ctx))
+			require.ErrorIs(t, err, pgxTransactor.ErrNoTransaction)
+
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,145 @@
+//go:build cgo
+
+package sqlx_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	sqlxTransactor "github.com/Thiht/transactor/sqlx"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/marcboeker/go-duckdb"
+	"github.com/stretchr/testify/require"
+)
+
+// The DuckDB driver requires cgo, so these tests are in their own file.
+func TestIntegrationTransactorDuckDB(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	initScript, err := os.ReadFile("../testdata/init_duckdb.sql")
+	require.NoError(t, err)
+
+	db, err := sqlx.Connect("duckdb", "")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	_, err = db.Exec(string(initScript))
+	require.NoError(t, err)
+
+	reset := func() {
+		t.Helper()
+		_, err := db.Exec("UPDATE balances SET amount = 100 WHERE id = 1")
+		require.NoError(t, err)
+	}
+
+	strategy, err := sqlxTransactor.NestedTransactionsAuto(ctx, db)
+	require.NoError(t, err)
+
+	transactor, dbGetter := sqlxTransactor.NewTransactor(db, strategy)
+
+	readAmount := func(ctx context.Context) int {
+		t.Helper()
+		var amount int
+		err := dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+		require.NoError(t, err)
+		return amount
+	}
+
+	t.Run("it should rollback the transaction", func(t *testing.T) {
+		t.Cleanup(reset)
+
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+			require.NoError(t, err)
+
+			return errors.New("an error occurred")
+		})
+		require.Error(t, err)
+
+		require.Equal(t, 100, readAmount(ctx))
+	})
+
+	t.Run("it should commit the transaction", func(t *testing.T) {
+		t.Cleanup(reset)
+
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			amount := readAmount(ctx)
+			require.Equal(t, 100, amount)
+
+			_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = ? WHERE id = 1", amount+10)
+			return err
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, 110, readAmount(ctx))
+	})
+
+	t.Run("with nested transactions", func(t *testing.T) {
+		t.Run("it should commit the nested transactions with the outermost transaction", func(t *testing.T) {
+			t.Cleanup(reset)
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					require.Equal(t, 50, readAmount(ctx))
+
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 70 WHERE id = 1")
+					return err
+				})
+			})
+			require.NoError(t, err)
+
+			require.Equal(t, 70, readAmount(ctx))
+		})
+
+		t.Run("it should rollback the outermost transaction when a nested transaction is rolled back", func(t *testing.T) {
+			t.Cleanup(reset)
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 70 WHERE id = 1")
+					require.NoError(t, err)
+
+					return errors.New("an error occurred")
+				})
+				require.Error(t, err)
+
+				return nil
+			})
+			require.ErrorIs(t, err, sqlxTransactor.ErrRollbackOnly)
+
+			require.Equal(t, 100, readAmount(ctx))
+		})
+
+		t.Run("it should rollback the outermost transaction when a nested statement fails", func(t *testing.T) {
+			t.Cleanup(reset)
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "INSERT INTO balances (id, amount) VALUES (1, 0)")
+					return err
+				})
+				require.Error(t, err)
+
+				return nil
+			})
+			require.ErrorIs(t, err, sqlxTransactor.ErrRollbackOnly)
+
+			require.Equal(t, 100, readAmount(ctx))
+		})
+	})
+}
@@ -2,12 +2,20 @@ package sqlx_test
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	sqlxTransactor "github.com/Thiht/transactor/sqlx"
 	"github.com/jmoiron/sqlx"
+	"github.com/nakagami/firebirdsql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -116,6 +124,31 @@ func TestTransactor(t *testing.T) {
 
 			require.NoError(t, mock.ExpectationsWereMet())
 		})
+
+		t.Run("it should execute the statements on the transaction", func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+			})
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+			transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+
+			mock.ExpectBegin()
+			mock.ExpectExec("UPDATE balances").WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				return err
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
 	})
 
 	t.Run("with nested transactions savepoints", func(t *testing.T) {
@@ -134,8 +167,8 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -164,8 +197,8 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -195,7 +228,7 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -224,8 +257,8 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("RELEASE SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -254,8 +287,8 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("RELEASE SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -284,8 +317,8 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
 			mock.ExpectRollback()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -314,10 +347,10 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("RELEASE SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_2").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_2").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -355,8 +388,9 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsMSSQL)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVE TRANSACTION sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("ROLLBACK TRANSACTION sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SAVE TRANSACTION sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT XACT_STATE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(1))
+			mock.ExpectExec("ROLLBACK TRANSACTION sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -385,8 +419,9 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsMSSQL)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVE TRANSACTION sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("ROLLBACK TRANSACTION sp_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
+			mock.ExpectExec("SAVE TRANSACTION sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT XACT_STATE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(1))
+			mock.ExpectExec("ROLLBACK TRANSACTION sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -416,7 +451,7 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsMSSQL)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVE TRANSACTION sp_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
+			mock.ExpectExec("SAVE TRANSACTION sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -431,6 +466,139 @@ func TestTransactor(t *testing.T) {
 
 			require.NoError(t, mock.ExpectationsWereMet())
 		})
+
+		t.Run("it should roll back the outermost transaction when a nested rollback finds it doomed", func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+			})
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsMSSQL)
+
+			mock.ExpectBegin()
+			mock.ExpectExec("SAVE TRANSACTION sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT XACT_STATE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(-1))
+			mock.ExpectRollback()
+
+			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				err := transactor.WithinTransaction(ctx, func(_ context.Context) error {
+					return errors.New("an error occurred")
+				})
+				require.ErrorContains(t, err, "an error occurred")
+				require.ErrorIs(t, err, sqlxTransactor.ErrTransactionDoomed)
+
+				return nil // The failure of the nested transaction is ignored
+			})
+			require.ErrorIs(t, err, sqlxTransactor.ErrTransactionDoomed)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+
+		t.Run("it should not roll back to a savepoint when the transaction was rolled back entirely", func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+			})
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsMSSQL)
+
+			mock.ExpectBegin()
+			mock.ExpectExec("SAVE TRANSACTION sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT XACT_STATE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(0))
+			mock.ExpectRollback()
+
+			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				return transactor.WithinTransaction(ctx, func(_ context.Context) error {
+					return errors.New("an error occurred")
+				})
+			})
+			require.ErrorContains(t, err, "an error occurred")
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+
+		t.Run("it should set XACT_ABORT when beginning the outermost transactions", func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+			})
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsMSSQL, sqlxTransactor.WithMSSQL(sqlxTransactor.MSSQL{XactAbort: true}))
+
+			mock.ExpectBegin()
+			mock.ExpectExec("SET XACT_ABORT ON").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SAVE TRANSACTION sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+
+			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				return transactor.WithinTransaction(ctx, func(_ context.Context) error {
+					return nil
+				})
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+
+		t.Run("it should set XACT_ABORT when beginning the read-only transactions", func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+			})
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsMSSQL, sqlxTransactor.WithMSSQL(sqlxTransactor.MSSQL{XactAbort: true, Snapshot: true}))
+
+			mock.ExpectBegin()
+			mock.ExpectExec("SET XACT_ABORT ON").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+
+			err = transactor.WithinTransaction(sqlxTransactor.WithReadOnly(context.Background()), func(context.Context) error {
+				return nil
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+
+		t.Run("it should fail to begin the transaction if XACT_ABORT can't be set", func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+			})
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsMSSQL, sqlxTransactor.WithMSSQL(sqlxTransactor.MSSQL{XactAbort: true}))
+
+			mock.ExpectBegin()
+			mock.ExpectExec("SET XACT_ABORT ON").WillReturnError(assert.AnError)
+			mock.ExpectRollback()
+
+			err = transactor.WithinTransaction(context.Background(), func(context.Context) error {
+				return nil
+			})
+			require.ErrorIs(t, err, assert.AnError)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
 	})
 
 	t.Run("with nested transactions oracle", func(t *testing.T) {
@@ -449,8 +617,8 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsOracle)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -479,8 +647,8 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsOracle)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
-			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -510,7 +678,101 @@ func TestTransactor(t *testing.T) {
 			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsOracle)
 
 			mock.ExpectBegin()
-			mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
+			mock.ExpectCommit()
+
+			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				err := transactor.WithinTransaction(ctx, func(_ context.Context) error {
+					return nil
+				})
+				require.Error(t, err)
+
+				return nil
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	})
+
+	t.Run("with nested transactions db2", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("it should rollback the nested transaction in case of error", func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+			})
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsDB2)
+
+			mock.ExpectBegin()
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1 ON ROLLBACK RETAIN CURSORS").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+
+			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				err := transactor.WithinTransaction(ctx, func(_ context.Context) error {
+					return errors.New("an error occurred")
+				})
+				require.Error(t, err)
+
+				return nil
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+
+		t.Run("it should return the original error in case of failure to rollback a nested transaction", func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+			})
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsDB2)
+
+			mock.ExpectBegin()
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1 ON ROLLBACK RETAIN CURSORS").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
+			mock.ExpectCommit()
+
+			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				err := transactor.WithinTransaction(ctx, func(_ context.Context) error {
+					return errors.New("an error occurred")
+				})
+				require.Error(t, err)
+				require.ErrorContains(t, err, "an error occurred")
+
+				return nil
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+
+		t.Run("it should return an error in case of failure to begin a nested transaction", func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+			})
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+			transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsDB2)
+
+			mock.ExpectBegin()
+			mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1 ON ROLLBACK RETAIN CURSORS").WillReturnResult(sqlmock.NewResult(0, 0)).WillReturnError(assert.AnError)
 			mock.ExpectCommit()
 
 			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
@@ -562,3 +824,3491 @@ func TestIsWithinTransaction(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should return immediately if no transaction is running", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+
+		aborted, err := transactor.Shutdown(context.Background())
+		require.NoError(t, err)
+		require.Zero(t, aborted)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should refuse new transactions", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+
+		_, err = transactor.Shutdown(context.Background())
+		require.NoError(t, err)
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			return nil
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrShuttingDown)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should wait for running transactions and let them use nested transactions", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		started := make(chan struct{})
+		shutdown := make(chan struct{})
+		errs := make(chan error, 1)
+		go func() {
+			errs <- transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				close(started)
+				<-shutdown
+
+				return transactor.WithinTransaction(ctx, func(_ context.Context) error {
+					return nil
+				})
+			})
+		}()
+
+		<-started
+		go func() {
+			// Give Shutdown some time to start waiting
+			time.Sleep(50 * time.Millisecond)
+			close(shutdown)
+		}()
+
+		aborted, err := transactor.Shutdown(context.Background())
+		require.NoError(t, err)
+		require.Zero(t, aborted)
+		require.NoError(t, <-errs)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should abort the running transactions when the context expires", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		started := make(chan struct{})
+		errs := make(chan error, 1)
+		go func() {
+			errs <- transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				close(started)
+				<-ctx.Done()
+
+				return context.Cause(ctx)
+			})
+		}()
+
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		t.Cleanup(cancel)
+
+		aborted, err := transactor.Shutdown(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 1, aborted)
+		require.ErrorIs(t, <-errs, sqlxTransactor.ErrShuttingDown)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should cancel the context of a transaction once it's done", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		var txCtx context.Context
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			txCtx = ctx
+			return nil
+		})
+		require.NoError(t, err)
+		require.ErrorIs(t, txCtx.Err(), context.Canceled)
+
+		aborted, err := transactor.Shutdown(context.Background())
+		require.NoError(t, err)
+		require.Zero(t, aborted)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not wait for the aborted transactions to be rolled back", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		started := make(chan struct{})
+		errs := make(chan error, 1)
+		go func() {
+			errs <- transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				close(started)
+				<-ctx.Done()
+				time.Sleep(50 * time.Millisecond) // Slow to react to the cancellation
+
+				return context.Cause(ctx)
+			})
+		}()
+
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		t.Cleanup(cancel)
+
+		aborted, err := transactor.Shutdown(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 1, aborted)
+		require.Error(t, mock.ExpectationsWereMet()) // Not rolled back yet when Shutdown returned
+
+		require.ErrorIs(t, <-errs, sqlxTransactor.ErrShuttingDown)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAdmissionControl(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should reject the transactions waiting longer than the max wait", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithAdmissionControl(sqlxTransactor.AdmissionControl{
+			MaxConcurrentTransactions: 1,
+			MaxWait:                   50 * time.Millisecond,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			err := transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+				return nil
+			})
+			require.ErrorIs(t, err, sqlxTransactor.ErrAdmissionTimeout)
+
+			stats := transactor.AdmissionStats()
+			require.Equal(t, 1, stats.InFlight)
+			require.Equal(t, int64(1), stats.Rejected)
+			require.Equal(t, int64(1), stats.WaitCount)
+			require.GreaterOrEqual(t, stats.WaitDuration, 50*time.Millisecond)
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Zero(t, transactor.AdmissionStats().InFlight)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not apply to nested transactions", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithAdmissionControl(sqlxTransactor.AdmissionControl{
+			MaxConcurrentTransactions: 1,
+			MaxWait:                   50 * time.Millisecond,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(_ context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should admit the waiting transactions by priority", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		var waits []sqlxTransactor.Priority
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithAdmissionControl(sqlxTransactor.AdmissionControl{
+			MaxConcurrentTransactions: 1,
+			OnWait: func(priority sqlxTransactor.Priority, _ time.Duration, err error) {
+				assert.NoError(t, err)
+				waits = append(waits, priority)
+			},
+		}))
+
+		for range 3 {
+			mock.ExpectBegin()
+			mock.ExpectCommit()
+		}
+
+		release := make(chan struct{})
+		errs := make(chan error, 3)
+		go func() {
+			errs <- transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+		require.Eventually(t, func() bool {
+			return transactor.AdmissionStats().InFlight == 1
+		}, time.Second, time.Millisecond)
+
+		for i, priority := range []sqlxTransactor.Priority{sqlxTransactor.PriorityLow, sqlxTransactor.PriorityHigh} {
+			go func() {
+				errs <- transactor.WithinTransaction(sqlxTransactor.WithPriority(context.Background(), priority), func(_ context.Context) error {
+					return nil
+				})
+			}()
+			require.Eventually(t, func() bool {
+				return transactor.AdmissionStats().Waiting == i+1
+			}, time.Second, time.Millisecond)
+		}
+
+		close(release)
+		for range 3 {
+			require.NoError(t, <-errs)
+		}
+
+		require.Equal(t, []sqlxTransactor.Priority{sqlxTransactor.PriorityNormal, sqlxTransactor.PriorityHigh, sqlxTransactor.PriorityLow}, waits)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should panic if the maximum number of transactions isn't positive", func(t *testing.T) {
+		t.Parallel()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		require.Panics(t, func() {
+			sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithAdmissionControl(sqlxTransactor.AdmissionControl{
+				MaxConcurrentTransactions: 0,
+			}))
+		})
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	connectionErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	t.Run("it should fail fast after consecutive connection failures", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithCircuitBreaker(sqlxTransactor.CircuitBreaker{
+			FailureThreshold: 2,
+			OpenDuration:     time.Hour,
+		}))
+
+		mock.ExpectBegin().WillReturnError(connectionErr)
+		mock.ExpectBegin().WillReturnError(connectionErr)
+
+		for range 2 {
+			err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+				return nil
+			})
+			require.ErrorIs(t, err, connectionErr)
+		}
+		require.Equal(t, sqlxTransactor.CircuitOpen, transactor.CircuitState())
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			return nil
+		})
+		var circuitOpenErr *sqlxTransactor.CircuitOpenError
+		require.ErrorAs(t, err, &circuitOpenErr)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not count the errors returned by the callback", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithCircuitBreaker(sqlxTransactor.CircuitBreaker{
+			FailureThreshold: 1,
+			OpenDuration:     time.Hour,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			return errors.New("an error occurred")
+		})
+		require.Error(t, err)
+		require.Equal(t, sqlxTransactor.CircuitClosed, transactor.CircuitState())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should close again after a successful probe transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		var states []sqlxTransactor.CircuitState
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithCircuitBreaker(sqlxTransactor.CircuitBreaker{
+			FailureThreshold: 1,
+			OpenDuration:     50 * time.Millisecond,
+			OnStateChange: func(_, to sqlxTransactor.CircuitState) {
+				states = append(states, to)
+			},
+		}))
+
+		mock.ExpectBegin().WillReturnError(connectionErr)
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			return nil
+		})
+		require.ErrorIs(t, err, connectionErr)
+
+		time.Sleep(50 * time.Millisecond)
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []sqlxTransactor.CircuitState{sqlxTransactor.CircuitOpen, sqlxTransactor.CircuitHalfOpen, sqlxTransactor.CircuitClosed}, states)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not count the connection errors returned by the callback", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithCircuitBreaker(sqlxTransactor.CircuitBreaker{
+			FailureThreshold: 1,
+			OpenDuration:     time.Hour,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			return connectionErr
+		})
+		require.ErrorIs(t, err, connectionErr)
+		require.Equal(t, sqlxTransactor.CircuitClosed, transactor.CircuitState())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not count the expired contexts as connection failures", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithCircuitBreaker(sqlxTransactor.CircuitBreaker{
+			FailureThreshold: 1,
+			OpenDuration:     time.Hour,
+		}))
+
+		mock.ExpectBegin().WillReturnError(&net.OpError{Op: "read", Net: "tcp", Err: context.DeadlineExceeded})
+		mock.ExpectBegin().WillReturnError(io.EOF)
+
+		for range 2 {
+			err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+				return nil
+			})
+			require.Error(t, err)
+		}
+		require.Equal(t, sqlxTransactor.CircuitClosed, transactor.CircuitState())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should panic if the configuration is invalid", func(t *testing.T) {
+		t.Parallel()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		for _, config := range []sqlxTransactor.CircuitBreaker{
+			{FailureThreshold: 0, OpenDuration: time.Second},
+			{FailureThreshold: 1, OpenDuration: 0},
+		} {
+			require.Panics(t, func() {
+				sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithCircuitBreaker(config))
+			})
+		}
+	})
+}
+
+func TestDeadlockDetection(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should report a query waiting for a connection held by its own transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+		db.SetMaxOpenConns(1)
+
+		var reported *sqlxTransactor.DeadlockError
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithDeadlockDetection(sqlxTransactor.DeadlockDetection{
+			Threshold: 50 * time.Millisecond,
+			OnDeadlock: func(err *sqlxTransactor.DeadlockError) {
+				reported = err
+			},
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			// The transaction context is not used, so the query waits for the connection held by the transaction
+			_, err := dbGetter(context.Background()).ExecContext(context.Background(), "UPDATE balances SET amount = 50 WHERE id = 1")
+			return err
+		})
+		var deadlockErr *sqlxTransactor.DeadlockError
+		require.ErrorAs(t, err, &deadlockErr)
+		require.Same(t, reported, deadlockErr)
+		require.NotEmpty(t, deadlockErr.HolderStack)
+		require.NotEmpty(t, deadlockErr.WaiterStack)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should report a new transaction waiting for a connection held by the current transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+		db.SetMaxOpenConns(1)
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithDeadlockDetection(sqlxTransactor.DeadlockDetection{
+			Threshold: 50 * time.Millisecond,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			return transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+				return nil
+			})
+		})
+		var deadlockErr *sqlxTransactor.DeadlockError
+		require.ErrorAs(t, err, &deadlockErr)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not report queries made when the pool is not exhausted", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+		db.SetMaxOpenConns(2)
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithDeadlockDetection(sqlxTransactor.DeadlockDetection{
+			Threshold: 50 * time.Millisecond,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE balances").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			_, err := dbGetter(context.Background()).ExecContext(context.Background(), "UPDATE balances SET amount = 50 WHERE id = 1")
+			return err
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not report a slow query once it acquired its connection", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		db.SetMaxOpenConns(2)
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithDeadlockDetection(sqlxTransactor.DeadlockDetection{
+			Threshold: 50 * time.Millisecond,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE balances").WillDelayFor(150 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			// The query exhausts the pool while it's running, but it already acquired its connection
+			_, err := dbGetter(context.Background()).ExecContext(context.Background(), "UPDATE balances SET amount = 50 WHERE id = 1")
+			return err
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should panic if the threshold isn't positive", func(t *testing.T) {
+		t.Parallel()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		require.Panics(t, func() {
+			sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithDeadlockDetection(sqlxTransactor.DeadlockDetection{
+				Threshold: 0,
+			}))
+		})
+	})
+
+	t.Run("it should return the deadlock error with the row of a query", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+		db.SetMaxOpenConns(1)
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithDeadlockDetection(sqlxTransactor.DeadlockDetection{
+			Threshold: 50 * time.Millisecond,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			var amount int
+			return dbGetter(context.Background()).QueryRowContext(context.Background(), "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+		})
+		var deadlockErr *sqlxTransactor.DeadlockError
+		require.ErrorAs(t, err, &deadlockErr)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should release the connection of a query before its rows are closed", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+		db.SetMaxOpenConns(2)
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithDeadlockDetection(sqlxTransactor.DeadlockDetection{
+			Threshold: 50 * time.Millisecond,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT amount").WillReturnRows(sqlmock.NewRows([]string{"amount"}).AddRow(100))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(_ context.Context) error {
+			rows, err := dbGetter(context.Background()).QueryContext(context.Background(), "SELECT amount FROM balances WHERE id = 1")
+			require.NoError(t, err)
+			defer rows.Close()
+			require.Equal(t, 1, db.Stats().InUse) // Only the connection of the transaction
+
+			var amount int
+			require.True(t, rows.Next())
+			require.NoError(t, rows.Scan(&amount))
+			require.Equal(t, 100, amount)
+
+			return rows.Err()
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWithoutTransaction(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should remove the transaction from the context", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			ctx = sqlxTransactor.WithoutTransaction(ctx)
+			assert.False(t, sqlxTransactor.IsWithinTransaction(ctx))
+			assert.Equal(t, dbGetter(context.Background()), dbGetter(ctx))
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should be canceled once the transaction returned, unlike the detached context", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		mock.ExpectExec("UPDATE balances").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		var withoutTxCtx, detachedCtx context.Context
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			withoutTxCtx = sqlxTransactor.WithoutTransaction(ctx)
+			detachedCtx = sqlxTransactor.DetachedContext(ctx)
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.ErrorIs(t, withoutTxCtx.Err(), context.Canceled)
+		require.NoError(t, detachedCtx.Err())
+		_, err = dbGetter(detachedCtx).ExecContext(detachedCtx, "UPDATE balances SET amount = 50 WHERE id = 1")
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should remove the state of the transaction from the context", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithOracle(sqlxTransactor.OracleTransaction{}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SET TRANSACTION READ ONLY").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(sqlxTransactor.WithReadOnly(context.Background()), func(ctx context.Context) error {
+			// Begins a read-write transaction
+			return transactor.WithinTransaction(sqlxTransactor.WithoutTransaction(ctx), func(context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDetachedContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should remove the transaction from the context and detach it from its cancellation", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var detachedCtx context.Context
+		err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			detachedCtx = sqlxTransactor.DetachedContext(ctx)
+			assert.False(t, sqlxTransactor.IsWithinTransaction(detachedCtx))
+			assert.Equal(t, dbGetter(context.Background()), dbGetter(detachedCtx))
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		cancel()
+		require.NoError(t, detachedCtx.Err())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestConcurrencySafeTransactions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should serialize the statements executed concurrently within a transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithConcurrencySafeTransactions())
+
+		const goroutines = 10
+
+		mock.MatchExpectationsInOrder(false)
+		mock.ExpectBegin()
+		for range goroutines {
+			mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("alice").AddRow("bob"))
+		}
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			var wg sync.WaitGroup
+			for range goroutines {
+				wg.Go(func() {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE users SET name = name")
+					assert.NoError(t, err)
+
+					rows, err := dbGetter(ctx).QueryContext(ctx, "SELECT name FROM users")
+					if !assert.NoError(t, err) {
+						return
+					}
+					defer rows.Close()
+
+					var names []string
+					for rows.Next() {
+						var name string
+						assert.NoError(t, rows.Scan(&name))
+						names = append(names, name)
+					}
+					assert.NoError(t, rows.Err())
+					assert.Equal(t, []string{"alice", "bob"}, names)
+				})
+			}
+			wg.Wait()
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should free the transaction before the rows are read", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithConcurrencySafeTransactions())
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		mock.ExpectQuery("SELECT name FROM users").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("alice"))
+		mock.ExpectQuery("SELECT name FROM users").WithArgs(2).WillReturnError(sql.ErrNoRows)
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			rows, err := dbGetter(ctx).QueryContext(ctx, "SELECT id FROM users")
+			require.NoError(t, err)
+			defer rows.Close()
+
+			var names []string
+			for rows.Next() {
+				var id int
+				require.NoError(t, rows.Scan(&id))
+
+				var name string
+				err := dbGetter(ctx).QueryRowContext(ctx, "SELECT name FROM users WHERE id = ?", id).Scan(&name)
+				if errors.Is(err, sql.ErrNoRows) {
+					continue
+				}
+				require.NoError(t, err)
+				names = append(names, name)
+			}
+			require.NoError(t, rows.Err())
+			assert.Equal(t, []string{"alice"}, names)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should scan the buffered rows with the mapper of the DB", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithConcurrencySafeTransactions())
+
+		type user struct {
+			ID   int    `db:"user_id"`
+			Name string `db:"user_name"`
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, user_name FROM users").WillReturnRows(sqlmock.NewRows([]string{"user_id", "user_name"}).AddRow(1, "alice").AddRow(2, "bob"))
+		mock.ExpectQuery("SELECT user_id, user_name FROM users").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"user_id", "user_name"}).AddRow(1, "alice"))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			var users []user
+			require.NoError(t, dbGetter(ctx).SelectContext(ctx, &users, "SELECT user_id, user_name FROM users"))
+			assert.Equal(t, []user{{ID: 1, Name: "alice"}, {ID: 2, Name: "bob"}}, users)
+
+			var u user
+			require.NoError(t, dbGetter(ctx).GetContext(ctx, &u, "SELECT user_id, user_name FROM users WHERE user_id = ?", 1))
+			assert.Equal(t, user{ID: 1, Name: "alice"}, u)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should keep the column types of the rows", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithConcurrencySafeTransactions())
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT name FROM users").WillReturnRows(
+			mock.NewRowsWithColumnDefinition(sqlmock.NewColumn("name").OfType("VARCHAR", "").WithLength(255).Nullable(true)).AddRow("alice"),
+		)
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			rows, err := dbGetter(ctx).QueryContext(ctx, "SELECT name FROM users")
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+
+			columnTypes, err := rows.ColumnTypes()
+			require.NoError(t, err)
+			require.Len(t, columnTypes, 1)
+			assert.Equal(t, "VARCHAR", columnTypes[0].DatabaseTypeName())
+			length, ok := columnTypes[0].Length()
+			assert.True(t, ok)
+			assert.Equal(t, int64(255), length)
+			nullable, ok := columnTypes[0].Nullable()
+			assert.True(t, ok)
+			assert.True(t, nullable)
+
+			return rows.Err()
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStrictMode(t *testing.T) {
+	t.Parallel()
+
+	// recordMisuses returns a strict mode recording the misuses instead of panicking
+	recordMisuses := func() (sqlxTransactor.StrictMode, func() []sqlxTransactor.MisuseKind) {
+		var mu sync.Mutex
+		var misuses []sqlxTransactor.MisuseKind
+
+		return sqlxTransactor.StrictMode{
+			OnMisuse: func(err *sqlxTransactor.MisuseError) {
+				mu.Lock()
+				defer mu.Unlock()
+				misuses = append(misuses, err.Kind)
+			},
+		}, func() []sqlxTransactor.MisuseKind {
+			mu.Lock()
+			defer mu.Unlock()
+			return misuses
+		}
+	}
+
+	t.Run("it should detect a transaction used after WithinTransaction returned", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		strictMode, misuses := recordMisuses()
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithStrictMode(strictMode))
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		var txCtx context.Context
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			txCtx = ctx
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Empty(t, misuses())
+
+		_, err = dbGetter(txCtx).ExecContext(txCtx, "UPDATE users SET name = name")
+		require.Error(t, err)
+		assert.Equal(t, []sqlxTransactor.MisuseKind{sqlxTransactor.MisuseUseAfterReturn}, misuses())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should panic by default", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithStrictMode(sqlxTransactor.StrictMode{}))
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		var txCtx context.Context
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			txCtx = ctx
+			return nil
+		})
+		require.NoError(t, err)
+
+		func() {
+			defer func() {
+				misuseErr, ok := recover().(*sqlxTransactor.MisuseError)
+				require.True(t, ok)
+				assert.Equal(t, sqlxTransactor.MisuseUseAfterReturn, misuseErr.Kind)
+			}()
+
+			_, _ = dbGetter(txCtx).ExecContext(txCtx, "UPDATE users SET name = name")
+		}()
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should detect a transaction used by several goroutines concurrently", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		strictMode, misuses := recordMisuses()
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithStrictMode(strictMode))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE users").WillDelayFor(100 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			var wg sync.WaitGroup
+			wg.Go(func() {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE users SET name = name")
+				assert.NoError(t, err)
+			})
+			time.Sleep(10 * time.Millisecond)
+			wg.Go(func() {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE users SET name = name")
+				assert.NoError(t, err)
+			})
+			wg.Wait()
+
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []sqlxTransactor.MisuseKind{sqlxTransactor.MisuseConcurrentUse}, misuses())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should detect rows left open at commit", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		strictMode, misuses := recordMisuses()
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithStrictMode(strictMode))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("alice"))
+		mock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("alice"))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			rows, err := dbGetter(ctx).QueryContext(ctx, "SELECT name FROM users")
+			require.NoError(t, err)
+			require.NoError(t, rows.Close())
+
+			_, err = dbGetter(ctx).QueryxContext(ctx, "SELECT name FROM users") //nolint:sqlclosecheck
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []sqlxTransactor.MisuseKind{sqlxTransactor.MisuseRowsOpenAtCommit}, misuses())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should detect a nested transaction failure ignored by the callback", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		strictMode, misuses := recordMisuses()
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithStrictMode(strictMode))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnError(assert.AnError)
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			_ = transactor.WithinTransaction(ctx, func(_ context.Context) error {
+				return nil
+			})
+
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []sqlxTransactor.MisuseKind{sqlxTransactor.MisuseNestedFailureIgnored}, misuses())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not report a nested transaction rolled back by the callback", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		strictMode, misuses := recordMisuses()
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithStrictMode(strictMode))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE users SET name = name")
+				require.NoError(t, err)
+
+				return errors.New("an error occurred")
+			})
+			require.Error(t, err)
+
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Empty(t, misuses())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTxGetter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should return an error if the context is not within a transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		_, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+
+		_, err = dbGetter.TxGetter()(context.Background())
+		require.ErrorIs(t, err, sqlxTransactor.ErrNoTransaction)
+	})
+
+	t.Run("it should return the current transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+		txGetter := dbGetter.TxGetter()
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			tx, err := txGetter(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, dbGetter(ctx), tx)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should return the DB handler within a fake transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewFakeTransactor(sqlxDB)
+		txGetter := dbGetter.TxGetter()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			tx, err := txGetter(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, sqlxDB, tx)
+
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("it should return an error without the fake transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewFakeTransactor(sqlxDB)
+		txGetter := dbGetter.TxGetter()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			_, err := txGetter(sqlxTransactor.WithoutTransaction(ctx))
+			require.ErrorIs(t, err, sqlxTransactor.ErrNoTransaction)
+
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestNoTxGetter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should return the original DB handler within a transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+		noTxGetter := dbGetter.NoTxGetter()
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			assert.Equal(t, sqlxDB, noTxGetter(ctx))
+			assert.True(t, sqlxTransactor.IsWithinTransaction(ctx))
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWithinSavepoint(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should release the savepoint if the callback succeeds", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("RELEASE SAVEPOINT insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinSavepoint(ctx, "insert_user", func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "INSERT INTO users (name) VALUES ('alice')")
+				return err
+			})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should rollback to the savepoint and return the error if the callback fails", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsMSSQL)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVE TRANSACTION insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO users").WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT XACT_STATE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(1))
+		mock.ExpectExec("ROLLBACK TRANSACTION insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			err := transactor.WithinSavepoint(ctx, "insert_user", func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "INSERT INTO users (name) VALUES ('alice')")
+				return err
+			})
+			require.ErrorIs(t, err, assert.AnError)
+
+			_, err = dbGetter(ctx).ExecContext(ctx, "UPDATE users SET name = 'alice'")
+			return err
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should return a savepoint error if the rollback to the savepoint fails", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT insert_user").WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		fnErr := errors.New("an error occurred")
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinSavepoint(ctx, "insert_user", func(_ context.Context) error {
+				return fnErr
+			})
+		})
+		var savepointErr *sqlxTransactor.SavepointError
+		require.ErrorAs(t, err, &savepointErr)
+		assert.Equal(t, "rollback to", savepointErr.Op)
+		require.ErrorIs(t, err, assert.AnError)
+		require.ErrorIs(t, err, fnErr)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should return an error outside of a transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
+
+		err = transactor.WithinSavepoint(context.Background(), "insert_user", func(_ context.Context) error {
+			return nil
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrNoTransaction)
+	})
+
+	t.Run("it should return an error if the savepoint name is invalid", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinSavepoint(ctx, "insert_user; DROP TABLE users", func(_ context.Context) error {
+				return nil
+			})
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrInvalidSavepointName)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should return an error if the strategy doesn't support savepoints", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone)
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinSavepoint(ctx, "insert_user", func(_ context.Context) error {
+				return nil
+			})
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrSavepointsNotSupported)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should return an error before creating the savepoint if its name exceeds the limit of the strategy", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsMSSQL)
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinSavepoint(ctx, "insert_user_with_a_very_long_name", func(_ context.Context) error {
+				return nil
+			})
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrInvalidSavepointName)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTrySavepoint(t *testing.T) {
+	t.Parallel()
+
+	isAnError := func(err error) bool {
+		return errors.Is(err, assert.AnError)
+	}
+
+	t.Run("it should swallow a recoverable error", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			ok, err := transactor.TrySavepoint(ctx, "insert_user", func(_ context.Context) error {
+				return assert.AnError
+			}, isAnError)
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should return an unrecoverable error", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			ok, err := transactor.TrySavepoint(ctx, "insert_user", func(_ context.Context) error {
+				return errors.New("an error occurred")
+			}, isAnError)
+			assert.False(t, ok)
+
+			return err
+		})
+		require.Error(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should report the success of the callback", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsOracle)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			ok, err := transactor.TrySavepoint(ctx, "insert_user", func(_ context.Context) error {
+				return nil
+			}, isAnError)
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStatementSavepoints(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should release the savepoint of a successful statement", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithStatementSavepoints())
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_0").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("alice").AddRow("bob"))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_0").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		var names []string
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			rows, err := dbGetter(ctx).QueryContext(ctx, "SELECT name FROM users")
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+
+			for rows.Next() {
+				var name string
+				if err := rows.Scan(&name); err != nil {
+					return err
+				}
+				names = append(names, name)
+			}
+
+			return rows.Err()
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"alice", "bob"}, names)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should rollback to the savepoint of a failed statement and keep the transaction usable", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithStatementSavepoints())
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_0").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO users").WillReturnError(assert.AnError)
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_0").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_0").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_0").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			_, err := dbGetter(ctx).ExecContext(ctx, "INSERT INTO users (name) VALUES ('alice')")
+			require.ErrorIs(t, err, assert.AnError)
+
+			_, err = dbGetter(ctx).ExecContext(ctx, "UPDATE users SET name = 'alice'")
+			return err
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should return a savepoint error if the rollback to the savepoint fails", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithStatementSavepoints())
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_0").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT name FROM users").WillReturnError(assert.AnError)
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_0").WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			var name string
+			return dbGetter(ctx).QueryRowContext(ctx, "SELECT name FROM users").Scan(&name)
+		})
+
+		var savepointErr *sqlxTransactor.SavepointError
+		require.ErrorAs(t, err, &savepointErr)
+		require.Equal(t, "rollback to", savepointErr.Op)
+		require.ErrorIs(t, err, sql.ErrConnDone)
+		require.ErrorIs(t, savepointErr.Cause, assert.AnError)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should fail the statements if the nested transactions strategy doesn't support savepoints", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsNone, sqlxTransactor.WithStatementSavepoints())
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE users SET name = 'alice'")
+			return err
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrSavepointsNotSupported)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not use savepoints outside of a transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		_, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithStatementSavepoints())
+
+		mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err = dbGetter(context.Background()).ExecContext(context.Background(), "UPDATE users SET name = 'alice'")
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should name the savepoints with the savepoint prefix", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithStatementSavepoints(), sqlxTransactor.WithSavepointPrefix("app_"))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("^SAVEPOINT app_[a-z0-9]{8}_0$").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("^RELEASE SAVEPOINT app_[a-z0-9]{8}_0$").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE users SET name = name")
+			return err
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSavepointNaming(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should name the savepoints with the prefix and an id unique to the transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithSavepointPrefix("app_"))
+
+		var names []string
+		for range 2 {
+			mock.ExpectBegin()
+			mock.ExpectExec(`SAVEPOINT app_[a-z0-9]{8}_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`SAVEPOINT app_[a-z0-9]{8}_2`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`RELEASE SAVEPOINT app_[a-z0-9]{8}_2`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`RELEASE SAVEPOINT app_[a-z0-9]{8}_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+
+			err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				_, ok := sqlxTransactor.CurrentSavepoint(ctx)
+				require.False(t, ok)
+
+				return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					outer, ok := sqlxTransactor.CurrentSavepoint(ctx)
+					require.True(t, ok)
+
+					return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+						inner, ok := sqlxTransactor.CurrentSavepoint(ctx)
+						require.True(t, ok)
+						require.Equal(t, strings.TrimSuffix(outer, "_1"), strings.TrimSuffix(inner, "_2"))
+
+						names = append(names, outer)
+						return nil
+					})
+				})
+			})
+			require.NoError(t, err)
+		}
+
+		require.Regexp(t, `^app_[a-z0-9]{8}_1$`, names[0])
+		require.NotEqual(t, names[0], names[1])
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should panic if the prefix is not a valid identifier", func(t *testing.T) {
+		t.Parallel()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		defer func() {
+			err, ok := recover().(error)
+			require.True(t, ok)
+			require.ErrorIs(t, err, sqlxTransactor.ErrInvalidSavepointName)
+		}()
+
+		sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithSavepointPrefix("1; DROP TABLE users; --"))
+	})
+
+	t.Run("it should fail the nested transactions whose names exceed the identifier length of the strategy", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsOracle, sqlxTransactor.WithSavepointPrefix("my_application_savepoint_"))
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrInvalidSavepointName)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestNestedTransactionsAuto(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should detect SQL Server from its version", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		mock.ExpectQuery(`SELECT sqlite_version\(\)`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT @@VERSION`).WillReturnRows(sqlmock.NewRows([]string{""}).AddRow("Microsoft SQL Server 2022 (RTM) - 16.0.1000.6 (X64)"))
+
+		strategy, err := sqlxTransactor.NestedTransactionsAuto(context.Background(), sqlxDB)
+		require.NoError(t, err)
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, strategy)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVE TRANSACTION sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should detect PostgreSQL from its version", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		mock.ExpectQuery(`SELECT sqlite_version\(\)`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT @@VERSION`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT version\(\)`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("PostgreSQL 16.2 on x86_64-pc-linux-gnu"))
+
+		strategy, err := sqlxTransactor.NestedTransactionsAuto(context.Background(), sqlxDB)
+		require.NoError(t, err)
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, strategy)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should detect Db2 from its version", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		mock.ExpectQuery(`SELECT sqlite_version\(\)`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT @@VERSION`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT version\(\)`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT banner FROM v\$version`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT service_level FROM sysibmadm.env_inst_info`).WillReturnRows(sqlmock.NewRows([]string{"service_level"}).AddRow("DB2 v11.5.9.0"))
+
+		strategy, err := sqlxTransactor.NestedTransactionsAuto(context.Background(), sqlxDB)
+		require.NoError(t, err)
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, strategy)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1 ON ROLLBACK RETAIN CURSORS").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should detect the database from the driver name", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "oracle")
+
+		strategy, err := sqlxTransactor.NestedTransactionsAuto(context.Background(), sqlxDB)
+		require.NoError(t, err)
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, strategy)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should fail if the database can't be detected", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		mock.ExpectQuery(`SELECT sqlite_version\(\)`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT @@VERSION`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT version\(\)`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("SomeSQL 1.0"))
+		mock.ExpectQuery(`SELECT banner FROM v\$version`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT service_level FROM sysibmadm.env_inst_info`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT rdb\$get_context\('SYSTEM', 'ENGINE_VERSION'\) FROM rdb\$database`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT library_version FROM pragma_version\(\)`).WillReturnError(assert.AnError)
+
+		_, err = sqlxTransactor.NestedTransactionsAuto(context.Background(), sqlxDB)
+		require.ErrorIs(t, err, sqlxTransactor.ErrUnknownDatabase)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should detect CockroachDB from its version", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		mock.ExpectQuery(`SELECT sqlite_version\(\)`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT @@VERSION`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT version\(\)`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("CockroachDB CCL v24.1.0 (x86_64-pc-linux-gnu, built 2024/05/15 21:28:29, go1.22.2 X:nocoverageredesign)"))
+
+		strategy, err := sqlxTransactor.NestedTransactionsAuto(context.Background(), sqlxDB)
+		require.NoError(t, err)
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, strategy)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(context.Context) error {
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should tell CockroachDB apart from PostgreSQL when using a PostgreSQL driver", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`SELECT version\(\)`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("CockroachDB CCL v24.1.0 (x86_64-pc-linux-gnu, built 2024/05/15 21:28:29, go1.22.2 X:nocoverageredesign)"))
+
+		strategy, err := sqlxTransactor.NestedTransactionsAuto(context.Background(), sqlxDB)
+		require.NoError(t, err)
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, strategy)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(context.Context) error {
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should report the capabilities of the database", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		for range 5 {
+			mock.ExpectBegin()
+			mock.ExpectRollback()
+		}
+
+		report, err := sqlxTransactor.Probe(context.Background(), sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
+		require.NoError(t, err)
+		require.False(t, report.Supported())
+
+		supported := map[string]bool{}
+		for _, check := range report.Checks {
+			supported[check.Name] = check.Supported()
+		}
+		require.Equal(t, map[string]bool{
+			"savepoint":                        true,
+			"release savepoint":                true,
+			"rollback to savepoint":            false,
+			"read only":                        true,
+			"isolation level Read Uncommitted": true,
+			"isolation level Read Committed":   true,
+			"isolation level Repeatable Read":  true,
+			"isolation level Snapshot":         true,
+			"isolation level Serializable":     true,
+		}, supported)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should fail if a transaction can't be started", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		mock.ExpectBegin().WillReturnError(assert.AnError)
+
+		_, err = sqlxTransactor.Probe(context.Background(), sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
+		require.ErrorIs(t, err, assert.AnError)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should ignore the optional checks to tell whether the strategy is supported", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		mock.ExpectBegin().WillReturnError(assert.AnError)
+		for range 5 {
+			mock.ExpectBegin().WillReturnError(assert.AnError)
+		}
+
+		report, err := sqlxTransactor.Probe(context.Background(), sqlxDB, sqlxTransactor.NestedTransactionsSavepoints)
+		require.NoError(t, err)
+		require.True(t, report.Supported())
+
+		for _, check := range report.Checks {
+			if !check.Supported() {
+				require.True(t, check.Optional, check.Name)
+			}
+		}
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// recordingStrategy is a custom strategy recording the calls to its hooks.
+type recordingStrategy struct {
+	calls         []string
+	maxNameLength int
+}
+
+func (s *recordingStrategy) BeginNested(ctx context.Context, tx sqlxTransactor.NestedTx) error {
+	return s.record(ctx, "BEGIN NESTED", tx)
+}
+
+func (s *recordingStrategy) CommitNested(ctx context.Context, tx sqlxTransactor.NestedTx) error {
+	return s.record(ctx, "COMMIT NESTED", tx)
+}
+
+func (s *recordingStrategy) RollbackNested(ctx context.Context, tx sqlxTransactor.NestedTx) error {
+	return s.record(ctx, "ROLLBACK NESTED", tx)
+}
+
+func (s *recordingStrategy) MaxSavepointNameLength() int {
+	return s.maxNameLength
+}
+
+func (s *recordingStrategy) record(ctx context.Context, hook string, tx sqlxTransactor.NestedTx) error {
+	s.calls = append(s.calls, hook+" "+strconv.Itoa(tx.Depth))
+	_, err := tx.Tx.ExecContext(ctx, hook+" "+tx.Savepoint)
+	return err
+}
+
+func TestNestedTransactionsWith(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should call the hooks of the strategy with the depth of the nested transactions", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		strategy := &recordingStrategy{maxNameLength: 63}
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsWith(strategy))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("BEGIN NESTED sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("BEGIN NESTED sp_[a-z0-9]+_2").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ROLLBACK NESTED sp_[a-z0-9]+_2").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("COMMIT NESTED sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				err := transactor.WithinTransaction(ctx, func(context.Context) error {
+					return assert.AnError
+				})
+				require.ErrorIs(t, err, assert.AnError)
+
+				return nil
+			})
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"BEGIN NESTED 1", "BEGIN NESTED 2", "ROLLBACK NESTED 2", "COMMIT NESTED 1"}, strategy.calls)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should use the strategy for the named savepoints", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		strategy := &recordingStrategy{maxNameLength: 63}
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsWith(strategy))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("BEGIN NESTED my_savepoint").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("COMMIT NESTED my_savepoint").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinSavepoint(ctx, "my_savepoint", func(context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"BEGIN NESTED 1", "COMMIT NESTED 1"}, strategy.calls)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should validate the savepoint names against the name length of the strategy", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		strategy := &recordingStrategy{maxNameLength: 16}
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsWith(strategy), sqlxTransactor.WithSavepointPrefix("long_prefix_"))
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrInvalidSavepointName)
+		require.Empty(t, strategy.calls)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string {
+	return "SQLSTATE " + string(e)
+}
+
+func (e sqlStateError) SQLState() string {
+	return string(e)
+}
+
+func TestNestedTransactionsCockroachDB(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should restart the transaction when the callback fails with a retryable error", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsCockroachDB)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE balances").WillReturnError(sqlStateError("40001"))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE balances").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("RELEASE SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		attempts := 0
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			attempts++
+			_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, attempts)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should restart the transaction when the release fails with a retryable error", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsCockroachDB)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT cockroach_restart").WillReturnError(sqlStateError("40001"))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		attempts := 0
+		err = transactor.WithinTransaction(context.Background(), func(context.Context) error {
+			attempts++
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, attempts)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should rollback the transaction when the callback fails with another error", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsCockroachDB)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		attempts := 0
+		err = transactor.WithinTransaction(context.Background(), func(context.Context) error {
+			attempts++
+			return sqlStateError("23505")
+		})
+		require.ErrorIs(t, err, sqlStateError("23505"))
+		require.Equal(t, 1, attempts)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should use savepoints for the nested transactions", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsCockroachDB)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			err := transactor.WithinTransaction(ctx, func(context.Context) error {
+				return sqlStateError("40001")
+			})
+			require.Error(t, err)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFirebirdTransaction(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should map the parameters to a SET TRANSACTION statement", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			transaction sqlxTransactor.FirebirdTransaction
+			expected    string
+		}{
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{},
+				expected:    "SET TRANSACTION READ WRITE WAIT ISOLATION LEVEL READ COMMITTED RECORD_VERSION",
+			},
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{Isolation: sqlxTransactor.FirebirdReadCommittedNoRecordVersion, NoWait: true},
+				expected:    "SET TRANSACTION READ WRITE NO WAIT ISOLATION LEVEL READ COMMITTED NO RECORD_VERSION",
+			},
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{Isolation: sqlxTransactor.FirebirdReadConsistency, LockTimeout: 5 * time.Second},
+				expected:    "SET TRANSACTION READ WRITE WAIT LOCK TIMEOUT 5 ISOLATION LEVEL READ COMMITTED READ CONSISTENCY",
+			},
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{LockTimeout: 1500 * time.Millisecond},
+				expected:    "SET TRANSACTION READ WRITE WAIT LOCK TIMEOUT 2 ISOLATION LEVEL READ COMMITTED RECORD_VERSION",
+			},
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{LockTimeout: time.Millisecond},
+				expected:    "SET TRANSACTION READ WRITE WAIT LOCK TIMEOUT 1 ISOLATION LEVEL READ COMMITTED RECORD_VERSION",
+			},
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{Isolation: sqlxTransactor.FirebirdSnapshot, ReadOnly: true},
+				expected:    "SET TRANSACTION READ ONLY WAIT ISOLATION LEVEL SNAPSHOT",
+			},
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{Isolation: sqlxTransactor.FirebirdSnapshotTableStability},
+				expected:    "SET TRANSACTION READ WRITE WAIT ISOLATION LEVEL SNAPSHOT TABLE STABILITY",
+			},
+		}
+		for _, tt := range tests {
+			statement, err := tt.transaction.SetTransaction()
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, statement)
+		}
+
+		_, err := sqlxTransactor.FirebirdTransaction{NoWait: true, LockTimeout: time.Second}.SetTransaction()
+		require.Error(t, err)
+	})
+
+	t.Run("it should map the parameters to the transaction options of the driver", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			transaction sqlxTransactor.FirebirdTransaction
+			expected    *sql.TxOptions
+		}{
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{},
+				expected:    &sql.TxOptions{Isolation: sql.LevelReadCommitted},
+			},
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{NoWait: true},
+				expected:    &sql.TxOptions{Isolation: firebirdsql.LevelReadCommittedNoWait},
+			},
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{ReadOnly: true, NoWait: true},
+				expected:    &sql.TxOptions{Isolation: firebirdsql.LevelReadCommittedNoWait, ReadOnly: true},
+			},
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{Isolation: sqlxTransactor.FirebirdSnapshot},
+				expected:    &sql.TxOptions{Isolation: sql.LevelRepeatableRead},
+			},
+			{
+				transaction: sqlxTransactor.FirebirdTransaction{Isolation: sqlxTransactor.FirebirdSnapshotTableStability},
+				expected:    &sql.TxOptions{Isolation: sql.LevelSerializable},
+			},
+		}
+		for _, tt := range tests {
+			opts, err := tt.transaction.TxOptions()
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, opts)
+		}
+
+		for _, transaction := range []sqlxTransactor.FirebirdTransaction{
+			{LockTimeout: time.Second},
+			{Isolation: sqlxTransactor.FirebirdReadCommittedNoRecordVersion},
+			{Isolation: sqlxTransactor.FirebirdReadConsistency},
+			{Isolation: sqlxTransactor.FirebirdSnapshot, NoWait: true},
+			{Isolation: sqlxTransactor.FirebirdSnapshot, ReadOnly: true},
+		} {
+			_, err := transaction.TxOptions()
+			require.ErrorIs(t, err, sqlxTransactor.ErrUnsupportedTxOptions)
+		}
+	})
+
+	t.Run("it should begin the outermost transactions with the transaction options", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsFirebird, sqlxTransactor.WithFirebird(sqlxTransactor.FirebirdTransaction{Isolation: sqlxTransactor.FirebirdSnapshot}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should fail the read-only transactions that the driver can't begin READ ONLY", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsFirebird, sqlxTransactor.WithFirebird(sqlxTransactor.FirebirdTransaction{Isolation: sqlxTransactor.FirebirdSnapshot}))
+
+		err = transactor.WithinTransaction(sqlxTransactor.WithReadOnly(context.Background()), func(context.Context) error {
+			return nil
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrUnsupportedTxOptions)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should panic if the parameters are invalid", func(t *testing.T) {
+		t.Parallel()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		defer func() {
+			_, ok := recover().(error)
+			require.True(t, ok)
+		}()
+
+		sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsFirebird, sqlxTransactor.WithFirebird(sqlxTransactor.FirebirdTransaction{NoWait: true, LockTimeout: time.Second}))
+	})
+
+	t.Run("it should panic if the driver can't begin a transaction with the parameters", func(t *testing.T) {
+		t.Parallel()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		defer func() {
+			err, ok := recover().(error)
+			require.True(t, ok)
+			require.ErrorIs(t, err, sqlxTransactor.ErrUnsupportedTxOptions)
+		}()
+
+		sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsFirebird, sqlxTransactor.WithFirebird(sqlxTransactor.FirebirdTransaction{LockTimeout: time.Second}))
+	})
+}
+
+func TestOracleTransaction(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should map the options to a SET TRANSACTION statement", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			transaction sqlxTransactor.OracleTransaction
+			expected    string
+		}{
+			{
+				transaction: sqlxTransactor.OracleTransaction{},
+				expected:    "",
+			},
+			{
+				transaction: sqlxTransactor.OracleTransaction{ReadOnly: true},
+				expected:    "SET TRANSACTION READ ONLY",
+			},
+			{
+				transaction: sqlxTransactor.OracleTransaction{Serializable: true, Name: "transfer"},
+				expected:    "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE NAME 'transfer'",
+			},
+			{
+				transaction: sqlxTransactor.OracleTransaction{Name: "alice's transfer"},
+				expected:    "SET TRANSACTION NAME 'alice''s transfer'",
+			},
+		}
+		for _, tt := range tests {
+			statement, err := tt.transaction.SetTransaction()
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, statement)
+		}
+
+		_, err := sqlxTransactor.OracleTransaction{ReadOnly: true, Serializable: true}.SetTransaction()
+		require.Error(t, err)
+
+		_, err = sqlxTransactor.OracleTransaction{Name: strings.Repeat("a", 256)}.SetTransaction()
+		require.Error(t, err)
+	})
+
+	t.Run("it should set the transaction as the first statement of the outermost transactions", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsOracle, sqlxTransactor.WithOracle(sqlxTransactor.OracleTransaction{Serializable: true, Name: "transfer"}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SET TRANSACTION ISOLATION LEVEL SERIALIZABLE NAME 'transfer'").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("SET TRANSACTION READ ONLY NAME 'transfer'").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		err = transactor.WithinTransaction(sqlxTransactor.WithReadOnly(context.Background()), func(context.Context) error {
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should rollback the transaction if it can't be set", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsOracle, sqlxTransactor.WithOracle(sqlxTransactor.OracleTransaction{ReadOnly: true}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SET TRANSACTION READ ONLY").WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(context.Context) error {
+			return nil
+		})
+		require.ErrorIs(t, err, assert.AnError)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should panic if the options are invalid", func(t *testing.T) {
+		t.Parallel()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		defer func() {
+			_, ok := recover().(error)
+			require.True(t, ok)
+		}()
+
+		sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsOracle, sqlxTransactor.WithOracle(sqlxTransactor.OracleTransaction{ReadOnly: true, Serializable: true}))
+	})
+}
+
+func TestImplicitCommitGuard(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should reject the statements implicitly committing the transaction", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithImplicitCommitGuard(sqlxTransactor.MySQLImplicitCommits()))
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			for _, query := range []string{
+				"CREATE TABLE users (id INT)",
+				"alter table users add column name text",
+				"  -- Reset the table\n  TRUNCATE users",
+				"/* Lock */ LOCK TABLES users WRITE",
+				"START TRANSACTION",
+			} {
+				_, err := dbGetter(ctx).ExecContext(ctx, query)
+				require.ErrorIs(t, err, sqlxTransactor.ErrImplicitCommit, query)
+			}
+
+			_, err := dbGetter(ctx).QueryContext(ctx, "DROP TABLE users")
+			require.ErrorIs(t, err, sqlxTransactor.ErrImplicitCommit)
+
+			_, err = dbGetter(ctx).PrepareContext(ctx, "DROP TABLE users")
+			require.ErrorIs(t, err, sqlxTransactor.ErrImplicitCommit)
+
+			err = dbGetter(ctx).QueryRowContext(ctx, "DROP TABLE users").Scan()
+			require.ErrorIs(t, err, sqlxTransactor.ErrImplicitCommit)
+			require.ErrorContains(t, err, "DROP statements commit the current transaction on MySQL")
+
+			return err
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrImplicitCommit)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should execute the other statements", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithImplicitCommitGuard(sqlxTransactor.MySQLImplicitCommits()))
+
+		mock.ExpectExec("CREATE TABLE users").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TEMPORARY TABLE users_import").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT created_at").WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow("2024-01-01"))
+		mock.ExpectCommit()
+
+		_, err = dbGetter(context.Background()).ExecContext(context.Background(), "CREATE TABLE users (id INT)")
+		require.NoError(t, err)
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			_, err := dbGetter(ctx).ExecContext(ctx, "CREATE TEMPORARY TABLE users_import (id INT)")
+			require.NoError(t, err)
+
+			_, err = dbGetter(ctx).ExecContext(ctx, "INSERT INTO users (id) VALUES (1)")
+			require.NoError(t, err)
+
+			var createdAt string
+			return dbGetter(ctx).QueryRowContext(ctx, "SELECT created_at FROM users WHERE id = 1").Scan(&createdAt)
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should use the statements of the database", func(t *testing.T) {
+		t.Parallel()
+
+		require.NoError(t, sqlxTransactor.OracleImplicitCommits().Check("ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY-MM-DD'"))
+		require.NoError(t, sqlxTransactor.OracleImplicitCommits().Check("BEGIN update_balances; END;"))
+		require.ErrorIs(t, sqlxTransactor.OracleImplicitCommits().Check("CREATE GLOBAL TEMPORARY TABLE users_import (id NUMBER)"), sqlxTransactor.ErrImplicitCommit)
+		require.ErrorIs(t, sqlxTransactor.MySQLImplicitCommits().Check("BEGIN"), sqlxTransactor.ErrImplicitCommit)
+		require.NoError(t, sqlxTransactor.MySQLImplicitCommits().Check("DROP TEMPORARY TABLE users_import"))
+		require.NoError(t, sqlxTransactor.MySQLImplicitCommits().Check("SELECT * FROM users FOR UPDATE"))
+		require.ErrorIs(t, sqlxTransactor.MySQLImplicitCommits().Check("/*!50100 CREATE TABLE users (id INT) */"), sqlxTransactor.ErrImplicitCommit)
+		require.ErrorIs(t, sqlxTransactor.MySQLImplicitCommits().Check("/*M!100100 DROP */ TABLE users"), sqlxTransactor.ErrImplicitCommit)
+		require.NoError(t, sqlxTransactor.MySQLImplicitCommits().Check("/*!40101 SET NAMES utf8mb4 */"))
+		require.NoError(t, sqlxTransactor.OracleImplicitCommits().Check("/*! CREATE */ SELECT 1 FROM dual"))
+	})
+	t.Run("it should reject the statements of the sqlx methods", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithImplicitCommitGuard(sqlxTransactor.MySQLImplicitCommits()))
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			_, err := dbGetter(ctx).NamedExecContext(ctx, "CREATE TABLE users (id INT)", map[string]any{})
+			require.ErrorIs(t, err, sqlxTransactor.ErrImplicitCommit)
+
+			var names []string
+			err = dbGetter(ctx).SelectContext(ctx, &names, "ANALYZE TABLE users")
+			require.ErrorIs(t, err, sqlxTransactor.ErrImplicitCommit)
+
+			require.Panics(t, func() {
+				dbGetter(ctx).MustExecContext(ctx, "DROP TABLE users")
+			})
+
+			return dbGetter(ctx).QueryRowxContext(ctx, "DROP TABLE users").Scan()
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrImplicitCommit)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMySQL(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should begin the transactions with a consistent snapshot", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithMySQL(sqlxTransactor.MySQL{ConsistentSnapshot: true}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`START TRANSACTION WITH CONSISTENT SNAPSHOT$`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		err = transactor.WithinTransaction(sqlxTransactor.WithReadOnly(context.Background()), func(context.Context) error {
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should rollback the transaction if the consistent snapshot can't be taken", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithMySQL(sqlxTransactor.MySQL{ConsistentSnapshot: true, ReadOnly: true}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY").WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(context.Context) error {
+			return nil
+		})
+		require.ErrorIs(t, err, assert.AnError)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSubtransactionBudget(t *testing.T) {
+	t.Parallel()
+
+	t.Run("it should warn when the nested transactions exceed the budget", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		var exceeded []int
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithSubtransactionBudget(sqlxTransactor.SubtransactionBudget{
+			Max: 1,
+			OnExceeded: func(_ context.Context, savepoints int) {
+				exceeded = append(exceeded, savepoints)
+			},
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			for range 2 {
+				if err := transactor.WithinTransaction(ctx, func(context.Context) error {
+					return nil
+				}); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []int{2}, exceeded)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should fail the nested transactions exceeding the budget", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithSubtransactionBudget(sqlxTransactor.SubtransactionBudget{
+			Max:      1,
+			Overflow: sqlxTransactor.SubtransactionsFail,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				return transactor.WithinTransaction(ctx, func(context.Context) error {
+					return nil
+				})
+			})
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrSubtransactionBudgetExceeded)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should flatten the nested transactions exceeding the budget", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithSubtransactionBudget(sqlxTransactor.SubtransactionBudget{
+			Max:      1,
+			Overflow: sqlxTransactor.SubtransactionsFlatten,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "INSERT INTO users (name) VALUES ('flattened')")
+					return err
+				})
+			})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should rollback the outermost transaction if a flattened nested transaction fails", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithSubtransactionBudget(sqlxTransactor.SubtransactionBudget{
+			Max:      1,
+			Overflow: sqlxTransactor.SubtransactionsFlatten,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				err := transactor.WithinTransaction(ctx, func(context.Context) error {
+					return assert.AnError
+				})
+				require.ErrorIs(t, err, assert.AnError)
+
+				return nil
+			})
+		})
+		require.ErrorIs(t, err, sqlxTransactor.ErrRollbackOnly)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should count the savepoints and the statement savepoints", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, dbGetter := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithStatementSavepoints(), sqlxTransactor.WithSubtransactionBudget(sqlxTransactor.SubtransactionBudget{
+			Max:      2,
+			Overflow: sqlxTransactor.SubtransactionsFail,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT insert_user").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_0").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_0").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			err := transactor.WithinSavepoint(ctx, "insert_user", func(context.Context) error {
+				return nil
+			})
+			require.NoError(t, err)
+
+			_, err = dbGetter(ctx).ExecContext(ctx, "UPDATE users SET name = 'alice'")
+			require.NoError(t, err)
+
+			err = transactor.WithinSavepoint(ctx, "insert_user", func(context.Context) error {
+				return nil
+			})
+			require.ErrorIs(t, err, sqlxTransactor.ErrSubtransactionBudgetExceeded)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not count the nested transactions of the strategies without savepoints", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsDuckDB, sqlxTransactor.WithSubtransactionBudget(sqlxTransactor.SubtransactionBudget{
+			Max:      1,
+			Overflow: sqlxTransactor.SubtransactionsFail,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			for range 2 {
+				if err := transactor.WithinTransaction(ctx, func(context.Context) error {
+					return nil
+				}); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not count the savepoints that couldn't be created", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithSubtransactionBudget(sqlxTransactor.SubtransactionBudget{
+			Max:      1,
+			Overflow: sqlxTransactor.SubtransactionsFail,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnError(assert.AnError)
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			err := transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+			require.ErrorIs(t, err, assert.AnError)
+
+			return transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should reset the budget when the outermost transaction restarts", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsCockroachDB, sqlxTransactor.WithSubtransactionBudget(sqlxTransactor.SubtransactionBudget{
+			Max:      1,
+			Overflow: sqlxTransactor.SubtransactionsFail,
+		}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		attempts := 0
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			attempts++
+			if err := transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			}); err != nil {
+				return err
+			}
+			if attempts == 1 {
+				return sqlStateError("40001")
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, attempts)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSessionSettings(t *testing.T) {
+	t.Parallel()
+
+	type tenantKey struct{}
+	settings := func(ctx context.Context) map[string]string {
+		tenant, ok := ctx.Value(tenantKey{}).(string)
+		if !ok {
+			return nil
+		}
+
+		return map[string]string{"app.user_id": "7", "app.tenant_id": tenant}
+	}
+
+	t.Run("it should apply the session settings to the outermost transactions", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithSessionSettings(settings))
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT set_config\(\$1, \$2, true\), set_config\(\$3, \$4, true\)`).
+			WithArgs("app.tenant_id", "42", "app.user_id", "7").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_[a-z0-9]+_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		ctx := context.WithValue(context.Background(), tenantKey{}, "42")
+		err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should not apply empty session settings", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithSessionSettings(settings))
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(context.Context) error {
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should rollback the transaction if the session settings can't be applied", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsSavepoints, sqlxTransactor.WithSessionSettings(settings))
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT set_config`).WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		ctx := context.WithValue(context.Background(), tenantKey{}, "42")
+		err = transactor.WithinTransaction(ctx, func(context.Context) error {
+			return nil
+		})
+		require.ErrorIs(t, err, assert.AnError)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("it should apply the session settings after the restart savepoint of CockroachDB, and again after a restart", func(t *testing.T) {
+		t.Parallel()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+		transactor, _ := sqlxTransactor.NewTransactor(sqlxDB, sqlxTransactor.NestedTransactionsCockroachDB, sqlxTransactor.WithSessionSettings(settings))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`SELECT set_config`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`SELECT set_config`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT cockroach_restart").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		attempts := 0
+		ctx := context.WithValue(context.Background(), tenantKey{}, "42")
+		err = transactor.WithinTransaction(ctx, func(context.Context) error {
+			attempts++
+			if attempts == 1 {
+				return sqlStateError("40001")
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, attempts)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
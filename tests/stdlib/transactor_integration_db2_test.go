@@ -0,0 +1,223 @@
+//go:build db2
+
+package stdlib_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thiht/transactor/stdlib"
+	"github.com/Thiht/transactor/stdlib/strategytest"
+	_ "github.com/ibmdb/go_ibm_db"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/log"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// The Db2 driver requires cgo and the IBM Data Server Driver (clidriver),
+// so these tests are in their own file and only built with the db2 tag.
+func TestIntegrationTransactorDb2(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testcontainers.DefaultLoggingHook = func(log.Logger) testcontainers.ContainerLifecycleHooks {
+		return testcontainers.ContainerLifecycleHooks{}
+	}
+
+	db2Container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "icr.io/db2_community/db2",
+			ExposedPorts: []string{"50000/tcp"},
+			Privileged:   true,
+			Env: map[string]string{
+				"LICENSE":           "accept",
+				"DB2INSTANCE":       "db2inst1",
+				"DB2INST1_PASSWORD": "Test1234!",
+				"DBNAME":            "testdb",
+			},
+			WaitingFor: wait.ForLog("Setup has completed").WithStartupTimeout(10 * time.Minute),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db2Container.Terminate(ctx))
+	})
+
+	containerPort, err := db2Container.MappedPort(ctx, "50000/tcp")
+	require.NoError(t, err)
+
+	dsn := "HOSTNAME=localhost;DATABASE=testdb;PORT=" + containerPort.Port() + ";UID=db2inst1;PWD=Test1234!"
+
+	initScript, err := os.ReadFile("../testdata/init_db2.sql")
+	require.NoError(t, err)
+
+	reset := func(db *sql.DB) {
+		t.Helper()
+		_, err := db.Exec("UPDATE balances SET amount = 100 WHERE id = 1")
+		require.NoError(t, err)
+	}
+
+	t.Run("with the go_ibm_db driver", func(t *testing.T) {
+		db, err := sql.Open("go_ibm_db", dsn)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, db.Close())
+		})
+
+		// Db2 executes a single statement at a time
+		for _, statement := range strings.Split(string(initScript), ";") {
+			if statement = strings.TrimSpace(statement); statement != "" {
+				_, err = db.Exec(statement)
+				require.NoError(t, err)
+			}
+		}
+
+		transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsDB2)
+
+		t.Run("it should rollback the transaction", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				return errors.New("an error occurred")
+			})
+			require.Error(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 100, amount)
+		})
+
+		t.Run("it should commit the transaction", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				var amount int
+				err := dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1 FOR UPDATE").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 100, amount)
+
+				_, err = dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = ? WHERE id = 1", amount+10)
+				require.NoError(t, err)
+
+				return err
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 110, amount)
+		})
+
+		t.Run("with nested transactions", func(t *testing.T) {
+			t.Run("it should rollback the nested transaction", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(db)
+				})
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+					require.NoError(t, err)
+
+					err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+						_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 70 WHERE id = 1")
+						require.NoError(t, err)
+
+						return errors.New("an error occurred")
+					})
+					require.Error(t, err)
+
+					var amount int
+					err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+					require.NoError(t, err)
+					require.Equal(t, 50, amount)
+
+					return nil
+				})
+				require.NoError(t, err)
+			})
+
+			t.Run("it should commit the nested transaction", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(db)
+				})
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+					require.NoError(t, err)
+
+					err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+						_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+						require.NoError(t, err)
+
+						return nil
+					})
+					require.NoError(t, err)
+
+					return nil
+				})
+				require.NoError(t, err)
+
+				var amount int
+				err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 120, amount)
+			})
+
+			t.Run("it should commit the second nested transaction and rollback the first nested transaction", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(db)
+				})
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+					require.NoError(t, err)
+
+					err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+						_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+						require.NoError(t, err)
+
+						err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+							_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+							require.NoError(t, err)
+
+							return nil
+						})
+						require.NoError(t, err)
+
+						return errors.New("an error occurred")
+					})
+					require.Error(t, err)
+
+					return nil
+				})
+				require.NoError(t, err)
+
+				var amount int
+				err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 110, amount)
+			})
+		})
+
+		t.Run("it should pass the strategy conformance tests", func(t *testing.T) {
+			strategytest.Run(t, db, stdlib.DB2Strategy())
+		})
+	})
+}
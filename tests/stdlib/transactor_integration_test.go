@@ -5,14 +5,20 @@ import (
 	"database/sql"
 	"errors"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/Thiht/transactor/stdlib"
+	"github.com/Thiht/transactor/stdlib/strategytest"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/microsoft/go-mssqldb"
+	_ "github.com/nakagami/firebirdsql"
 	go_ora "github.com/sijms/go-ora/v2"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/log"
@@ -195,6 +201,10 @@ func TestIntegrationTransactorPostgres(t *testing.T) {
 				require.Equal(t, 110, amount)
 			})
 		})
+
+		t.Run("it should pass the strategy conformance tests", func(t *testing.T) {
+			strategytest.Run(t, db, stdlib.SavepointsStrategy())
+		})
 	})
 }
 
@@ -366,6 +376,68 @@ func TestIntegrationTransactorMySQL(t *testing.T) {
 				require.Equal(t, 110, amount)
 			})
 		})
+
+		t.Run("it should pass the strategy conformance tests", func(t *testing.T) {
+			strategytest.Run(t, db, stdlib.SavepointsStrategy())
+		})
+
+		t.Run("with the mysql modes", func(t *testing.T) {
+			transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsSavepoints,
+				stdlib.WithMySQL(stdlib.MySQL{ConsistentSnapshot: true}),
+				stdlib.WithImplicitCommitGuard(stdlib.MySQLImplicitCommits()),
+			)
+
+			t.Run("it should take the snapshot when the transaction begins", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(db)
+				})
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := db.ExecContext(ctx, "UPDATE balances SET amount = 200 WHERE id = 1")
+					require.NoError(t, err)
+
+					var amount int
+					err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+					require.NoError(t, err)
+					require.Equal(t, 100, amount)
+
+					return nil
+				})
+				require.NoError(t, err)
+			})
+
+			t.Run("it should begin read-only transactions within a read-only context", func(t *testing.T) {
+				err := transactor.WithinTransaction(stdlib.WithReadOnly(ctx), func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+					require.Error(t, err)
+
+					return nil
+				})
+				require.NoError(t, err)
+			})
+
+			t.Run("it should reject the statements implicitly committing the transaction", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(db)
+				})
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+					require.NoError(t, err)
+
+					_, err = dbGetter(ctx).ExecContext(ctx, "CREATE TABLE transfers (id INTEGER PRIMARY KEY)")
+					require.ErrorIs(t, err, stdlib.ErrImplicitCommit)
+
+					return err
+				})
+				require.ErrorIs(t, err, stdlib.ErrImplicitCommit)
+
+				var amount int
+				err = db.QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 100, amount)
+			})
+		})
 	})
 }
 
@@ -528,6 +600,305 @@ func TestIntegrationTransactorSQLite(t *testing.T) {
 				require.Equal(t, 110, amount)
 			})
 		})
+
+		t.Run("it should rollback to a savepoint and keep the transaction usable", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				ok, err := transactor.TrySavepoint(ctx, "insert_balance", func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+					require.NoError(t, err)
+
+					_, err = dbGetter(ctx).ExecContext(ctx, "INSERT INTO balances (id, amount) VALUES (1, 0)")
+					return err
+				}, func(err error) bool {
+					return err != nil // The primary key is violated
+				})
+				require.NoError(t, err)
+				require.False(t, ok)
+
+				_, err = dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+				return err
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 110, amount)
+		})
+
+		t.Run("it should detect the nested transactions strategy", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			strategy, err := stdlib.NestedTransactionsAuto(ctx, db)
+			require.NoError(t, err)
+
+			transactor, dbGetter := stdlib.NewTransactor(db, strategy)
+
+			err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 0 WHERE id = 1")
+					require.NoError(t, err)
+
+					return errors.New("an error occurred")
+				})
+				require.Error(t, err)
+
+				return nil
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 50, amount)
+		})
+
+		t.Run("it should probe the capabilities of the database", func(t *testing.T) {
+			report, err := stdlib.Probe(ctx, db, stdlib.NestedTransactionsSavepoints)
+			require.NoError(t, err)
+			require.True(t, report.Supported(), report)
+		})
+
+		t.Run("it should rollback a failed statement to its savepoint and keep the transaction usable", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsSavepoints, stdlib.WithStatementSavepoints())
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				_, err = dbGetter(ctx).ExecContext(ctx, "INSERT INTO balances (id, amount) VALUES (1, 0)")
+				require.Error(t, err) // The primary key is violated
+
+				_, err = dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+				return err
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 60, amount)
+		})
+
+		t.Run("it should serialize the statements executed concurrently within a transaction", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsSavepoints, stdlib.WithConcurrencySafeTransactions())
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				var wg sync.WaitGroup
+				for range 10 {
+					wg.Go(func() {
+						var amount int
+						err := dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+						assert.NoError(t, err)
+
+						_, err = dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 1 WHERE id = 1")
+						assert.NoError(t, err)
+					})
+				}
+				wg.Wait()
+
+				return nil
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 110, amount)
+		})
+
+		t.Run("it should pass the strategy conformance tests", func(t *testing.T) {
+			strategytest.Run(t, db, stdlib.SavepointsStrategy())
+		})
+	})
+
+	t.Run("with a database file in WAL mode", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "transactor.db")
+		dsn := file + "?_pragma=journal_mode(WAL)"
+
+		db, err := sql.Open("sqlite", dsn)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, db.Close())
+		})
+
+		readers, err := sql.Open("sqlite", dsn)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, readers.Close())
+		})
+
+		_, err = db.Exec(string(initScript))
+		require.NoError(t, err)
+
+		transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsSavepoints, stdlib.WithSQLite(stdlib.SQLite{Readers: readers}))
+
+		t.Run("it should serialize the concurrent read-write transactions", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			var wg sync.WaitGroup
+			for range 10 {
+				wg.Go(func() {
+					err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+						var amount int
+						if err := dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount); err != nil {
+							return err
+						}
+						time.Sleep(time.Millisecond) // Lets the other transactions read before the write
+
+						_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = ? WHERE id = 1", amount+1)
+						return err
+					})
+					assert.NoError(t, err)
+				})
+			}
+			wg.Wait()
+
+			var amount int
+			err := dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 110, amount)
+		})
+
+		t.Run("it should take the write lock when the transaction begins", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			err := transactor.WithinTransaction(ctx, func(context.Context) error {
+				_, err := db.ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.ErrorContains(t, err, "SQLITE_BUSY")
+
+				return nil
+			})
+			require.NoError(t, err)
+		})
+
+		t.Run("it should fail to begin a read-write transaction from the context of the one holding the writer lock", func(t *testing.T) {
+			err := transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+				err := transactor.WithinTransaction(stdlib.DetachedContext(txCtx), func(context.Context) error {
+					return nil
+				})
+				require.ErrorIs(t, err, stdlib.ErrWriterLockHeld)
+
+				return nil
+			})
+			require.NoError(t, err)
+		})
+
+		t.Run("it should begin a read-write transaction from the context of a done one", func(t *testing.T) {
+			var detachedCtx context.Context
+			err := transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+				detachedCtx = stdlib.DetachedContext(txCtx)
+				return nil
+			})
+			require.NoError(t, err)
+
+			err = transactor.WithinTransaction(detachedCtx, func(context.Context) error {
+				return nil
+			})
+			require.NoError(t, err)
+		})
+
+		t.Run("it should begin the read-only transactions on the readers without waiting for the writer", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			err := transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+				_, err := dbGetter(txCtx).ExecContext(txCtx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				err = transactor.WithinTransaction(stdlib.WithReadOnly(ctx), func(ctx context.Context) error {
+					var amount int
+					err := dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+					require.NoError(t, err)
+					require.Equal(t, 100, amount)
+
+					_, err = dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 60 WHERE id = 1")
+					require.Error(t, err)
+
+					return nil
+				})
+				require.NoError(t, err)
+
+				return nil
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 50, amount)
+		})
+
+		t.Run("it should rollback the nested transaction", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 60 WHERE id = 1")
+					require.NoError(t, err)
+
+					return errors.New("an error occurred")
+				})
+				require.Error(t, err)
+
+				return nil
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 50, amount)
+		})
+
+		t.Run("it should begin the read-write transactions with BEGIN EXCLUSIVE", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsSavepoints, stdlib.WithSQLite(stdlib.SQLite{Exclusive: true}))
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := db.ExecContext(ctx, "UPDATE balances SET amount = 60 WHERE id = 1")
+				require.ErrorContains(t, err, "SQLITE_BUSY")
+
+				_, err = dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				return err
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 50, amount)
+		})
 	})
 }
 
@@ -715,23 +1086,112 @@ func TestIntegrationTransactorOracle(t *testing.T) {
 				require.Equal(t, 110, amount)
 			})
 		})
-	})
-}
 
-func TestIntegrationTransactorMSSQL(t *testing.T) {
-	t.Parallel()
+		t.Run("it should pass the strategy conformance tests", func(t *testing.T) {
+			strategytest.Run(t, db, stdlib.OracleStrategy())
+		})
 
-	ctx := context.Background()
+		t.Run("with the oracle transaction options", func(t *testing.T) {
+			transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsOracle, stdlib.WithOracle(stdlib.OracleTransaction{Serializable: true, Name: "transactor_test"}))
 
-	testcontainers.DefaultLoggingHook = func(log.Logger) testcontainers.ContainerLifecycleHooks {
-		return testcontainers.ContainerLifecycleHooks{}
-	}
+			t.Run("it should name the transaction", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(db)
+				})
 
-	mssqlContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: testcontainers.ContainerRequest{
-			Image:        "mcr.microsoft.com/mssql/server:2022-latest",
-			ExposedPorts: []string{"1433/tcp"},
-			Env: map[string]string{
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+					require.NoError(t, err)
+
+					var name string
+					err = dbGetter(ctx).QueryRowContext(ctx, "SELECT t.name FROM v$transaction t JOIN v$session s ON s.taddr = t.addr WHERE s.sid = SYS_CONTEXT('USERENV', 'SID')").Scan(&name)
+					require.NoError(t, err)
+					require.Equal(t, "transactor_test", name)
+
+					return nil
+				})
+				require.NoError(t, err)
+			})
+
+			t.Run("it should begin serializable transactions", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(db)
+				})
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					var amount int
+					err := dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+					require.NoError(t, err)
+					require.Equal(t, 100, amount)
+
+					_, err = db.ExecContext(ctx, "UPDATE balances SET amount = 200 WHERE id = 1")
+					require.NoError(t, err)
+
+					err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+					require.NoError(t, err)
+					require.Equal(t, 100, amount)
+
+					return nil
+				})
+				require.NoError(t, err)
+			})
+
+			t.Run("it should begin read-only transactions within a read-only context", func(t *testing.T) {
+				err := transactor.WithinTransaction(stdlib.WithReadOnly(ctx), func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+					require.Error(t, err)
+
+					return nil
+				})
+				require.NoError(t, err)
+
+				var amount int
+				err = db.QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 100, amount)
+			})
+		})
+
+		t.Run("it should reject the statements implicitly committing the transaction", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsOracle, stdlib.WithImplicitCommitGuard(stdlib.OracleImplicitCommits()))
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				_, err = dbGetter(ctx).ExecContext(ctx, "CREATE TABLE transfers (id INTEGER PRIMARY KEY)")
+				require.ErrorIs(t, err, stdlib.ErrImplicitCommit)
+
+				return err
+			})
+			require.ErrorIs(t, err, stdlib.ErrImplicitCommit)
+
+			var amount int
+			err = db.QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 100, amount)
+		})
+	})
+}
+
+func TestIntegrationTransactorMSSQL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testcontainers.DefaultLoggingHook = func(log.Logger) testcontainers.ContainerLifecycleHooks {
+		return testcontainers.ContainerLifecycleHooks{}
+	}
+
+	mssqlContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "mcr.microsoft.com/mssql/server:2022-latest",
+			ExposedPorts: []string{"1433/tcp"},
+			Env: map[string]string{
 				"ACCEPT_EULA":       "Y",
 				"MSSQL_SA_PASSWORD": "Test1234!",
 			},
@@ -903,5 +1363,417 @@ func TestIntegrationTransactorMSSQL(t *testing.T) {
 				require.Equal(t, 110, amount)
 			})
 		})
+
+		t.Run("it should pass the strategy conformance tests", func(t *testing.T) {
+			strategytest.Run(t, db, stdlib.MSSQLStrategy())
+		})
+
+		t.Run("with XACT_ABORT", func(t *testing.T) {
+			transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsMSSQL, stdlib.WithMSSQL(stdlib.MSSQL{XactAbort: true}))
+
+			t.Run("it should rollback the outermost transaction doomed by a nested transaction", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(db)
+				})
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+					require.NoError(t, err)
+
+					err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+						_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount / 0 WHERE id = 1")
+						return err
+					})
+					require.Error(t, err)
+
+					return nil // The failure of the nested transaction is ignored
+				})
+				require.ErrorIs(t, err, stdlib.ErrTransactionDoomed)
+
+				var amount int
+				err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 100, amount)
+			})
+		})
+
+		t.Run("with the snapshot isolation", func(t *testing.T) {
+			// The snapshot isolation can't be allowed on the master database
+			_, err := db.ExecContext(ctx, "CREATE DATABASE snapshots")
+			require.NoError(t, err)
+			_, err = db.ExecContext(ctx, "ALTER DATABASE snapshots SET ALLOW_SNAPSHOT_ISOLATION ON")
+			require.NoError(t, err)
+
+			snapshotDB, err := sql.Open("sqlserver", dsn+"?database=snapshots")
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, snapshotDB.Close())
+			})
+
+			_, err = snapshotDB.Exec(string(initScript))
+			require.NoError(t, err)
+
+			transactor, dbGetter := stdlib.NewTransactor(snapshotDB, stdlib.NestedTransactionsMSSQL, stdlib.WithMSSQL(stdlib.MSSQL{Snapshot: true}))
+
+			t.Run("it should read a snapshot of the database", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(snapshotDB)
+				})
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					var isolationLevel int
+					err := dbGetter(ctx).QueryRowContext(ctx, "SELECT transaction_isolation_level FROM sys.dm_exec_sessions WHERE session_id = @@SPID").Scan(&isolationLevel)
+					require.NoError(t, err)
+					require.Equal(t, 5, isolationLevel) // Snapshot
+
+					var amount int
+					err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+					require.NoError(t, err)
+					require.Equal(t, 100, amount)
+
+					_, err = snapshotDB.ExecContext(ctx, "UPDATE balances SET amount = 200 WHERE id = 1")
+					require.NoError(t, err)
+
+					err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+					require.NoError(t, err)
+					require.Equal(t, 100, amount)
+
+					return nil
+				})
+				require.NoError(t, err)
+			})
+		})
 	})
 }
+
+func TestIntegrationTransactorFirebird(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testcontainers.DefaultLoggingHook = func(log.Logger) testcontainers.ContainerLifecycleHooks {
+		return testcontainers.ContainerLifecycleHooks{}
+	}
+
+	firebirdContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "firebirdsql/firebird:5",
+			ExposedPorts: []string{"3050/tcp"},
+			Env: map[string]string{
+				"FIREBIRD_ROOT_PASSWORD": "Test1234!",
+				"FIREBIRD_DATABASE":      "transactor.fdb",
+			},
+			WaitingFor: wait.ForListeningPort("3050/tcp").WithStartupTimeout(time.Minute),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, firebirdContainer.Terminate(ctx))
+	})
+
+	containerPort, err := firebirdContainer.MappedPort(ctx, "3050/tcp")
+	require.NoError(t, err)
+
+	dsn := "SYSDBA:Test1234!@localhost:" + containerPort.Port() + "//var/lib/firebird/data/transactor.fdb"
+
+	initScript, err := os.ReadFile("../testdata/init_firebird.sql")
+	require.NoError(t, err)
+
+	reset := func(db *sql.DB) {
+		t.Helper()
+		_, err := db.Exec("UPDATE balances SET amount = 100 WHERE id = 1")
+		require.NoError(t, err)
+	}
+
+	t.Run("with the firebirdsql driver", func(t *testing.T) {
+		db, err := sql.Open("firebirdsql", dsn)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, db.Close())
+		})
+
+		// Firebird executes a single statement at a time
+		for _, statement := range strings.Split(string(initScript), ";") {
+			if statement = strings.TrimSpace(statement); statement != "" {
+				_, err = db.Exec(statement)
+				require.NoError(t, err)
+			}
+		}
+
+		transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsFirebird)
+
+		t.Run("it should rollback the transaction", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				return errors.New("an error occurred")
+			})
+			require.Error(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 100, amount)
+		})
+
+		t.Run("it should commit the transaction", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				var amount int
+				err := dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1 WITH LOCK").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 100, amount)
+
+				_, err = dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = ? WHERE id = 1", amount+10)
+				require.NoError(t, err)
+
+				return err
+			})
+			require.NoError(t, err)
+
+			var amount int
+			err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+			require.NoError(t, err)
+			require.Equal(t, 110, amount)
+		})
+
+		t.Run("with nested transactions", func(t *testing.T) {
+			t.Run("it should rollback the nested transaction", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(db)
+				})
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+					require.NoError(t, err)
+
+					err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+						_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 70 WHERE id = 1")
+						require.NoError(t, err)
+
+						return errors.New("an error occurred")
+					})
+					require.Error(t, err)
+
+					var amount int
+					err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+					require.NoError(t, err)
+					require.Equal(t, 50, amount)
+
+					return nil
+				})
+				require.NoError(t, err)
+			})
+
+			t.Run("it should commit the nested transaction", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(db)
+				})
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+					require.NoError(t, err)
+
+					err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+						_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+						require.NoError(t, err)
+
+						return nil
+					})
+					require.NoError(t, err)
+
+					return nil
+				})
+				require.NoError(t, err)
+
+				var amount int
+				err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 120, amount)
+			})
+
+			t.Run("it should commit the second nested transaction and rollback the first nested transaction", func(t *testing.T) {
+				t.Cleanup(func() {
+					reset(db)
+				})
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+					require.NoError(t, err)
+
+					err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+						_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+						require.NoError(t, err)
+
+						err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+							_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = amount + 10 WHERE id = 1")
+							require.NoError(t, err)
+
+							return nil
+						})
+						require.NoError(t, err)
+
+						return errors.New("an error occurred")
+					})
+					require.Error(t, err)
+
+					return nil
+				})
+				require.NoError(t, err)
+
+				var amount int
+				err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 110, amount)
+			})
+		})
+
+		t.Run("it should begin the transactions with the Firebird transaction parameters", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsFirebird, stdlib.WithFirebird(stdlib.FirebirdTransaction{
+				Isolation: stdlib.FirebirdSnapshot,
+			}))
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				var amount int
+				err := dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 100, amount)
+
+				_, err = db.ExecContext(ctx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				// The snapshot doesn't see the changes committed after the transaction began
+				err = dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount)
+				require.NoError(t, err)
+				require.Equal(t, 100, amount)
+
+				return nil
+			})
+			require.NoError(t, err)
+		})
+
+		t.Run("it should begin the transactions with the effective Firebird transaction parameters", func(t *testing.T) {
+			tests := []struct {
+				name           string
+				transaction    stdlib.FirebirdTransaction
+				readOnly       bool
+				isolationModes []int // MON$ISOLATION_MODE, READ COMMITTED runs as READ CONSISTENCY with the default configuration of Firebird 4 and later
+				lockTimeout    int   // MON$LOCK_TIMEOUT, -1 waits indefinitely and 0 doesn't wait
+			}{
+				{name: "READ COMMITTED", transaction: stdlib.FirebirdTransaction{}, isolationModes: []int{2, 4}, lockTimeout: -1},
+				{name: "READ COMMITTED NO WAIT", transaction: stdlib.FirebirdTransaction{NoWait: true}, isolationModes: []int{2, 4}, lockTimeout: 0},
+				{name: "READ ONLY", transaction: stdlib.FirebirdTransaction{}, readOnly: true, isolationModes: []int{2, 4}, lockTimeout: -1},
+				{name: "READ ONLY NO WAIT", transaction: stdlib.FirebirdTransaction{NoWait: true}, readOnly: true, isolationModes: []int{2, 4}, lockTimeout: 0},
+				{name: "SNAPSHOT", transaction: stdlib.FirebirdTransaction{Isolation: stdlib.FirebirdSnapshot}, isolationModes: []int{1}, lockTimeout: -1},
+				{name: "SNAPSHOT TABLE STABILITY", transaction: stdlib.FirebirdTransaction{Isolation: stdlib.FirebirdSnapshotTableStability}, isolationModes: []int{0}, lockTimeout: -1},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsFirebird, stdlib.WithFirebird(tt.transaction))
+
+					txCtx := ctx
+					if tt.readOnly {
+						txCtx = stdlib.WithReadOnly(ctx)
+					}
+
+					err := transactor.WithinTransaction(txCtx, func(ctx context.Context) error {
+						var isolationMode, lockTimeout, readOnly int
+						err := dbGetter(ctx).QueryRowContext(ctx, "SELECT MON$ISOLATION_MODE, MON$LOCK_TIMEOUT, MON$READ_ONLY FROM MON$TRANSACTIONS WHERE MON$TRANSACTION_ID = CURRENT_TRANSACTION").
+							Scan(&isolationMode, &lockTimeout, &readOnly)
+						require.NoError(t, err)
+						require.Contains(t, tt.isolationModes, isolationMode)
+						require.Equal(t, tt.lockTimeout, lockTimeout)
+						require.Equal(t, tt.readOnly, readOnly == 1)
+
+						return nil
+					})
+					require.NoError(t, err)
+				})
+			}
+		})
+
+		t.Run("it should fail on the lock conflicts without waiting with NO WAIT", func(t *testing.T) {
+			t.Cleanup(func() {
+				reset(db)
+			})
+
+			transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsFirebird, stdlib.WithFirebird(stdlib.FirebirdTransaction{
+				NoWait: true,
+			}))
+
+			err := transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+				_, err := dbGetter(txCtx).ExecContext(txCtx, "UPDATE balances SET amount = 50 WHERE id = 1")
+				require.NoError(t, err)
+
+				return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = 60 WHERE id = 1")
+					require.ErrorContains(t, err, "lock conflict")
+
+					return nil
+				})
+			})
+			require.NoError(t, err)
+		})
+
+		t.Run("it should pass the strategy conformance tests", func(t *testing.T) {
+			strategytest.Run(t, db, stdlib.FirebirdStrategy())
+		})
+	})
+}
+
+// BenchmarkStatementSavepointsSQLite measures the overhead of the statement savepoints on an in-memory database,
+// where it's mostly the cost of the additional statements. On a remote database, the additional round trips dominate.
+func BenchmarkStatementSavepointsSQLite(b *testing.B) {
+	ctx := context.Background()
+
+	initScript, err := os.ReadFile("../testdata/init_sqlite.sql")
+	require.NoError(b, err)
+
+	for _, bc := range []struct {
+		name string
+		opts []stdlib.Option
+	}{
+		{name: "without statement savepoints"},
+		{name: "with statement savepoints", opts: []stdlib.Option{stdlib.WithStatementSavepoints()}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			db, err := sql.Open("sqlite", ":memory:")
+			require.NoError(b, err)
+			b.Cleanup(func() {
+				require.NoError(b, db.Close())
+			})
+			db.SetMaxOpenConns(1)
+
+			_, err = db.Exec(string(initScript))
+			require.NoError(b, err)
+
+			transactor, dbGetter := stdlib.NewTransactor(db, stdlib.NestedTransactionsSavepoints, bc.opts...)
+
+			for b.Loop() {
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					var amount int
+					if err := dbGetter(ctx).QueryRowContext(ctx, "SELECT amount FROM balances WHERE id = 1").Scan(&amount); err != nil {
+						return err
+					}
+
+					_, err := dbGetter(ctx).ExecContext(ctx, "UPDATE balances SET amount = ? WHERE id = 1", amount+1)
+					return err
+				})
+				require.NoError(b, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+CREATE TABLE balances (
+  id BIGINT NOT NULL GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  amount BIGINT NOT NULL
+);
+
+INSERT INTO balances (amount) VALUES (100);
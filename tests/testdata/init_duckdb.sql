@@ -0,0 +1,6 @@
+CREATE TABLE balances (
+  id INTEGER PRIMARY KEY,
+  amount INTEGER NOT NULL
+);
+
+INSERT INTO balances (id, amount) VALUES (1, 100);
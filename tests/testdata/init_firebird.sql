@@ -0,0 +1,6 @@
+CREATE TABLE balances (
+  id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  amount INTEGER NOT NULL
+);
+
+INSERT INTO balances (amount) VALUES (100);